@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/dorianneto/bugfy/internal/api/model"
 	service "github.com/dorianneto/bugfy/internal/service"
 	"github.com/dorianneto/bugfy/util"
+	"github.com/go-chi/chi/v5"
 )
 
 type ErrorHandler struct {
@@ -28,12 +30,15 @@ func (h *ErrorHandler) CreateError(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The project is resolved from the ingest key, never trusted from the body.
+	req.ProjectID, _ = r.Context().Value("projectID").(string)
+
 	log.Printf("CreateError - Request received: projectID=%s", req.ProjectID)
 
 	e, err := h.errorService.CreateError(r.Context(), req)
 	if err != nil {
 		log.Printf("CreateError - Service error: %v", err)
-		util.WriteError(w, http.StatusInternalServerError, err.Error())
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
@@ -41,3 +46,76 @@ func (h *ErrorHandler) CreateError(w http.ResponseWriter, r *http.Request) {
 
 	util.WriteJSON(w, http.StatusCreated, e)
 }
+
+func (h *ErrorHandler) GetIssueEvents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	issueId := chi.URLParam(r, "issueId")
+
+	log.Printf("GetIssueEvents - Request received: id=%s, issueId=%s", id, issueId)
+
+	req := model.RequestGetEvents{Cursor: r.URL.Query().Get("cursor")}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+		req.Limit = limit
+	}
+
+	events, next, err := h.errorService.GetIssueEvents(r.Context(), id, issueId, req)
+	if err != nil {
+		log.Printf("GetIssueEvents - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	setNextLink(w, r, next)
+
+	util.WriteJSON(w, http.StatusOK, events)
+}
+
+func (h *ErrorHandler) GetLatestEvent(w http.ResponseWriter, r *http.Request) {
+	h.getIssueEdgeEvent(w, r, true)
+}
+
+func (h *ErrorHandler) GetOldestEvent(w http.ResponseWriter, r *http.Request) {
+	h.getIssueEdgeEvent(w, r, false)
+}
+
+func (h *ErrorHandler) getIssueEdgeEvent(w http.ResponseWriter, r *http.Request, newest bool) {
+	id := chi.URLParam(r, "id")
+	issueId := chi.URLParam(r, "issueId")
+
+	log.Printf("GetIssueEdgeEvent - Request received: id=%s, issueId=%s, newest=%t", id, issueId, newest)
+
+	event, err := h.errorService.GetIssueEdgeEvent(r.Context(), id, issueId, newest)
+	if err != nil {
+		log.Printf("GetIssueEdgeEvent - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, event)
+}
+
+func (h *ErrorHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	eventId := chi.URLParam(r, "eventId")
+
+	log.Printf("GetEvent - Request received: eventId=%s", eventId)
+
+	event, err := h.errorService.GetEvent(r.Context(), eventId, userID)
+	if err != nil {
+		log.Printf("GetEvent - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, event)
+}
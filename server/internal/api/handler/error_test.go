@@ -0,0 +1,192 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dorianneto/bugfy/db"
+	handler "github.com/dorianneto/bugfy/internal/api/handler"
+	"github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	service "github.com/dorianneto/bugfy/internal/service"
+	"github.com/dorianneto/bugfy/router"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// TestCreateErrorConcurrentGrouping fires parallel ingestion requests for a
+// single fingerprint and checks they are grouped into one issue whose count
+// matches exactly. It runs against the database at MONGODB_URI.
+func TestCreateErrorConcurrentGrouping(t *testing.T) {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+
+	// In-flight requests are bounded so that each one finishes well inside
+	// the services' request timeout, even on a slow database.
+	const (
+		requests    = 500
+		concurrency = 50
+	)
+
+	client, err := db.NewDatabase()
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	server, projectID, publicKey := newTestServer(t, client)
+	defer server.Close()
+
+	body, err := json.Marshal(model.RequestCreateError{
+		Message: "concurrent grouping test",
+		StackTrace: []model.StackFrame{
+			{Function: "main", Filename: "main.go", Line: 10, InApp: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	statuses := make(chan int, requests)
+
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/api/errors/", bytes.NewReader(body))
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Bugfy-Key", publicKey)
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			res.Body.Close()
+			statuses <- res.StatusCode
+		}()
+	}
+
+	wg.Wait()
+	close(statuses)
+
+	for status := range statuses {
+		if status != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, status)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	issues := client.Database("portobello").Collection(repo.ISSUE_COLLECTION)
+
+	n, err := issues.CountDocuments(ctx, bson.D{{Key: "project_id", Value: projectID}})
+	if err != nil {
+		t.Fatalf("failed to count issues: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 issue, got %d", n)
+	}
+
+	var issue repo.Issue
+	if err := issues.FindOne(ctx, bson.D{{Key: "project_id", Value: projectID}}).Decode(&issue); err != nil {
+		t.Fatalf("failed to fetch issue: %v", err)
+	}
+	if issue.Count != requests {
+		t.Fatalf("expected count %d, got %d", requests, issue.Count)
+	}
+}
+
+// newTestServer serves the application router and creates a project with
+// a key, returning the project's ID and public key. The project's data is
+// removed when the test ends.
+func newTestServer(t *testing.T, client *mongo.Client) (*httptest.Server, bson.ObjectID, string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userRepo := repo.NewUserRepository(client)
+	projectRepo := repo.NewProjectRepository(client)
+	errorRepo := repo.NewErrorRepository(client)
+	issueRepo := repo.NewIssueRepository(client)
+	organizationRepo := repo.NewOrganizationRepository(client)
+	activityRepo := repo.NewActivityRepository(client)
+	releaseRepo := repo.NewReleaseRepository(client)
+
+	if err := issueRepo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("failed to create indexes: %v", err)
+	}
+	if err := errorRepo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("failed to create indexes: %v", err)
+	}
+
+	// Projects are upserted by title, so both title and key are unique.
+	publicKey, err := util.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	project, err := projectRepo.CreateProject(ctx, &repo.Project{
+		Title: "Concurrency test " + publicKey,
+		Keys: []repo.ProjectKey{{
+			ID:        bson.NewObjectID(),
+			PublicKey: publicKey,
+			CreatedAt: time.Now(),
+		}},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	projectID := project.ID
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		database := client.Database("portobello")
+		filter := bson.D{{Key: "project_id", Value: projectID}}
+		_, _ = database.Collection(repo.ERROR_COLLECTION).DeleteMany(ctx, filter)
+		_, _ = database.Collection(repo.ISSUE_COLLECTION).DeleteMany(ctx, filter)
+		_, _ = database.Collection(repo.PROJECT_COLLECTION).DeleteOne(ctx, bson.D{{Key: "_id", Value: projectID}})
+	})
+
+	organizationService := service.NewOrganizationService(organizationRepo, userRepo)
+	userService := service.NewUserService(userRepo, organizationService)
+	projectService := service.NewProjectService(projectRepo, organizationService)
+	releaseService := service.NewReleaseService(releaseRepo)
+	issueService := service.NewIssueService(issueRepo, activityRepo, errorRepo, releaseService)
+	errorService := service.NewErrorService(errorRepo, projectRepo, issueService, releaseService, projectService)
+
+	r := router.SetupRouter(
+		handler.NewUserHandler(userService),
+		handler.NewProjectHandler(projectService, issueService, releaseService),
+		handler.NewErrorHandler(errorService),
+		handler.NewOrganizationHandler(organizationService, projectService),
+		projectService,
+	)
+
+	return httptest.NewServer(r), projectID, publicKey
+}
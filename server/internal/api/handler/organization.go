@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dorianneto/bugfy/internal/api/model"
+	service "github.com/dorianneto/bugfy/internal/service"
+	"github.com/dorianneto/bugfy/util"
+	"github.com/go-chi/chi/v5"
+)
+
+type OrganizationHandler struct {
+	organizationService *service.OrganizationService
+	projectService      *service.ProjectService
+}
+
+func NewOrganizationHandler(organizationService *service.OrganizationService, projectService *service.ProjectService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		projectService:      projectService,
+	}
+}
+
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req model.RequestCreateOrganization
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("CreateOrganization - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("CreateOrganization - Request received: name=%s", req.Name)
+
+	org, err := h.organizationService.CreateOrganization(r.Context(), userID, req)
+	if err != nil {
+		log.Printf("CreateOrganization - Service error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("CreateOrganization - Success: organization created with ID=%s, name=%s", org.ID, org.Name)
+
+	util.WriteJSON(w, http.StatusCreated, org)
+}
+
+func (h *OrganizationHandler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	orgs, err := h.organizationService.GetOrganizations(r.Context(), userID)
+	if err != nil {
+		log.Printf("GetOrganizations - Service error: %v", err)
+		util.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, orgs)
+}
+
+func (h *OrganizationHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	log.Printf("GetMembers - Request received: id=%s", id)
+
+	members, err := h.organizationService.GetMembers(r.Context(), id, userID)
+	if err != nil {
+		log.Printf("GetMembers - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, members)
+}
+
+func (h *OrganizationHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	memberId := chi.URLParam(r, "userId")
+
+	var req model.RequestUpdateMember
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("UpdateMember - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("UpdateMember - Request received: id=%s, userId=%s, role=%s", id, memberId, req.Role)
+
+	member, err := h.organizationService.UpdateMember(r.Context(), id, userID, memberId, req)
+	if err != nil {
+		log.Printf("UpdateMember - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	log.Printf("UpdateMember - Success: member ID=%s of organization ID=%s is now %s", memberId, id, member.Role)
+
+	util.WriteJSON(w, http.StatusOK, member)
+}
+
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	memberId := chi.URLParam(r, "userId")
+
+	log.Printf("RemoveMember - Request received: id=%s, userId=%s", id, memberId)
+
+	err := h.organizationService.RemoveMember(r.Context(), id, userID, memberId)
+	if err != nil {
+		log.Printf("RemoveMember - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("RemoveMember - Success: member ID=%s removed from organization ID=%s", memberId, id)
+
+	util.WriteJSON(w, http.StatusOK, map[string]string{"message": "member removed"})
+}
+
+func (h *OrganizationHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var req model.RequestCreateInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("CreateInvite - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("CreateInvite - Request received: id=%s, email=%s", id, req.Email)
+
+	invite, err := h.organizationService.CreateInvite(r.Context(), id, userID, req)
+	if err != nil {
+		log.Printf("CreateInvite - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	log.Printf("CreateInvite - Success: invite created with ID=%s for organization ID=%s", invite.ID, id)
+
+	util.WriteJSON(w, http.StatusCreated, invite)
+}
+
+func (h *OrganizationHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	org, err := h.organizationService.AcceptInvite(r.Context(), chi.URLParam(r, "token"), userID)
+	if err != nil {
+		log.Printf("AcceptInvite - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("AcceptInvite - Success: user ID=%s joined organization ID=%s", userID, org.ID)
+
+	util.WriteJSON(w, http.StatusOK, org)
+}
+
+func (h *OrganizationHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	log.Printf("GetProjects - Request received: id=%s", id)
+
+	projects, err := h.projectService.GetProjects(r.Context(), id, userID)
+	if err != nil {
+		log.Printf("GetProjects - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, projects)
+}
+
+// errorStatus maps the service's sentinel errors to HTTP status codes,
+// falling back to the given status for anything else.
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrOrganizationNotFound),
+		errors.Is(err, service.ErrMemberNotFound),
+		errors.Is(err, service.ErrProjectNotFound),
+		errors.Is(err, service.ErrProjectKeyNotFound),
+		errors.Is(err, service.ErrIssueNotFound),
+		errors.Is(err, service.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrUserExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidInvite),
+		errors.Is(err, service.ErrInvalidIssueQuery),
+		errors.Is(err, service.ErrInvalidIssueUpdate),
+		errors.Is(err, service.ErrInvalidEvent):
+		return http.StatusBadRequest
+	}
+
+	return fallback
+}
@@ -2,8 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dorianneto/bugfy/internal/api/model"
 	service "github.com/dorianneto/bugfy/internal/service"
@@ -14,12 +19,14 @@ import (
 type ProjectHandler struct {
 	projectService *service.ProjectService
 	issueService   *service.IssueService
+	releaseService *service.ReleaseService
 }
 
-func NewProjectHandler(projectService *service.ProjectService, issueService *service.IssueService) *ProjectHandler {
+func NewProjectHandler(projectService *service.ProjectService, issueService *service.IssueService, releaseService *service.ReleaseService) *ProjectHandler {
 	return &ProjectHandler{
 		projectService: projectService,
 		issueService:   issueService,
+		releaseService: releaseService,
 	}
 }
 
@@ -31,12 +38,18 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	log.Printf("CreateProject - Request received: title=%s", req.Title)
 
-	project, err := h.projectService.CreateProject(r.Context(), req)
+	project, err := h.projectService.CreateProject(r.Context(), userID, req)
 	if err != nil {
 		log.Printf("CreateProject - Service error: %v", err)
-		util.WriteError(w, http.StatusInternalServerError, err.Error())
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
@@ -50,14 +63,383 @@ func (h *ProjectHandler) GetIssues(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("GetIssues - Request received: id=%s", id)
 
-	issues, err := h.issueService.GetIssues(r.Context(), id)
+	req, err := parseGetIssues(r.URL.Query())
+	if err != nil {
+		log.Printf("GetIssues - Query error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	issues, next, err := h.issueService.GetIssues(r.Context(), id, req)
 	if err != nil {
 		log.Printf("GetIssues - Service error: %v", err)
-		util.WriteError(w, http.StatusInternalServerError, err.Error())
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
 	log.Printf("GetIssues - Success: issues fetched from ID=%s", id)
 
-	util.WriteJSON(w, http.StatusCreated, issues)
+	setNextLink(w, r, next)
+
+	util.WriteJSON(w, http.StatusOK, issues)
+}
+
+func (h *ProjectHandler) GetGroupingRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	log.Printf("GetGroupingRules - Request received: id=%s", id)
+
+	rules, err := h.projectService.GetGroupingRules(r.Context(), id)
+	if err != nil {
+		log.Printf("GetGroupingRules - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, rules)
+}
+
+func (h *ProjectHandler) UpdateGroupingRules(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req model.RequestUpdateGroupingRules
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("UpdateGroupingRules - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("UpdateGroupingRules - Request received: id=%s, rules=%d", id, len(req.Rules))
+
+	rules, err := h.projectService.UpdateGroupingRules(r.Context(), id, req)
+	if err != nil {
+		log.Printf("UpdateGroupingRules - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	log.Printf("UpdateGroupingRules - Success: rules updated for ID=%s", id)
+
+	util.WriteJSON(w, http.StatusOK, rules)
+}
+
+func (h *ProjectHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req model.RequestCreateProjectKey
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("CreateKey - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("CreateKey - Request received: id=%s, label=%s", id, req.Label)
+
+	key, err := h.projectService.CreateProjectKey(r.Context(), id, req)
+	if err != nil {
+		log.Printf("CreateKey - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	log.Printf("CreateKey - Success: key created with ID=%s for project ID=%s", key.ID, id)
+
+	util.WriteJSON(w, http.StatusCreated, key)
+}
+
+func (h *ProjectHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	log.Printf("GetKeys - Request received: id=%s", id)
+
+	keys, err := h.projectService.GetProjectKeys(r.Context(), id)
+	if err != nil {
+		log.Printf("GetKeys - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, keys)
+}
+
+func (h *ProjectHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	keyId := chi.URLParam(r, "keyId")
+
+	log.Printf("RevokeKey - Request received: id=%s, keyId=%s", id, keyId)
+
+	key, err := h.projectService.RevokeProjectKey(r.Context(), id, keyId)
+	if err != nil {
+		log.Printf("RevokeKey - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("RevokeKey - Success: key ID=%s revoked for project ID=%s", keyId, id)
+
+	util.WriteJSON(w, http.StatusOK, key)
+}
+
+func (h *ProjectHandler) UpdateIssue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	issueId := chi.URLParam(r, "issueId")
+
+	var req model.RequestUpdateIssue
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("UpdateIssue - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("UpdateIssue - Request received: id=%s, issueId=%s, status=%s", id, issueId, req.Status)
+
+	issue, err := h.issueService.UpdateIssueStatus(r.Context(), id, issueId, userID, req)
+	if err != nil {
+		log.Printf("UpdateIssue - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("UpdateIssue - Success: issue ID=%s updated", issueId)
+
+	util.WriteJSON(w, http.StatusOK, issue)
+}
+
+func (h *ProjectHandler) BulkUpdateIssues(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var req model.RequestBulkUpdateIssues
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("BulkUpdateIssues - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("BulkUpdateIssues - Request received: id=%s, issues=%d, status=%s", id, len(req.IDs), req.Status)
+
+	issues, err := h.issueService.BulkUpdateIssueStatus(r.Context(), id, userID, req)
+	if err != nil {
+		log.Printf("BulkUpdateIssues - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("BulkUpdateIssues - Success: %d issues updated in project ID=%s", len(issues), id)
+
+	util.WriteJSON(w, http.StatusOK, issues)
+}
+
+func (h *ProjectHandler) GetIssueActivity(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	issueId := chi.URLParam(r, "issueId")
+
+	log.Printf("GetIssueActivity - Request received: id=%s, issueId=%s", id, issueId)
+
+	activity, err := h.issueService.GetIssueActivity(r.Context(), id, issueId)
+	if err != nil {
+		log.Printf("GetIssueActivity - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, activity)
+}
+
+func (h *ProjectHandler) CreateRelease(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req model.RequestCreateRelease
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("CreateRelease - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("CreateRelease - Request received: id=%s, version=%s", id, req.Version)
+
+	release, err := h.releaseService.CreateRelease(r.Context(), id, req)
+	if err != nil {
+		log.Printf("CreateRelease - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	log.Printf("CreateRelease - Success: release %s recorded for project ID=%s", release.Version, id)
+
+	util.WriteJSON(w, http.StatusCreated, release)
+}
+
+func (h *ProjectHandler) GetReleases(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	log.Printf("GetReleases - Request received: id=%s", id)
+
+	releases, err := h.releaseService.GetReleases(r.Context(), id)
+	if err != nil {
+		log.Printf("GetReleases - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, releases)
+}
+
+func (h *ProjectHandler) GetIssueTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	issueId := chi.URLParam(r, "issueId")
+
+	log.Printf("GetIssueTags - Request received: id=%s, issueId=%s", id, issueId)
+
+	tags, err := h.issueService.GetIssueTags(r.Context(), id, issueId)
+	if err != nil {
+		log.Printf("GetIssueTags - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, tags)
+}
+
+func (h *ProjectHandler) MergeIssues(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var req model.RequestMergeIssues
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("MergeIssues - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("MergeIssues - Request received: id=%s, primary_id=%s, count=%d", id, req.PrimaryID, len(req.IDs))
+
+	issue, err := h.issueService.MergeIssues(r.Context(), id, userID, req)
+	if err != nil {
+		log.Printf("MergeIssues - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("MergeIssues - Success: issues merged into ID=%s", issue.ID)
+
+	util.WriteJSON(w, http.StatusOK, issue)
+}
+
+func (h *ProjectHandler) UnmergeIssue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	issueId := chi.URLParam(r, "issueId")
+
+	var req model.RequestUnmergeIssue
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("UnmergeIssue - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	log.Printf("UnmergeIssue - Request received: id=%s, issueId=%s, fingerprint=%s", id, issueId, req.Fingerprint)
+
+	issue, err := h.issueService.UnmergeIssue(r.Context(), id, issueId, userID, req)
+	if err != nil {
+		log.Printf("UnmergeIssue - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("UnmergeIssue - Success: issue ID=%s split from ID=%s", issue.ID, issueId)
+
+	util.WriteJSON(w, http.StatusOK, issue)
+}
+
+// setNextLink points the Link header at the page following r, if any.
+func setNextLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", cursor)
+	u.RawQuery = q.Encode()
+
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
+
+// parseGetIssues reads the filters, sort and pagination of an issue listing
+// from its query parameters.
+func parseGetIssues(q url.Values) (model.RequestGetIssues, error) {
+	req := model.RequestGetIssues{
+		Status:      q.Get("status"),
+		Environment: q.Get("environment"),
+		Release:     q.Get("release"),
+		Level:       q.Get("level"),
+		Query:       q.Get("query"),
+		Sort:        q.Get("sort"),
+		Cursor:      q.Get("cursor"),
+	}
+
+	for _, tag := range q["tag"] {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" || value == "" {
+			return req, fmt.Errorf("tag must be given as key:value")
+		}
+		if req.Tags == nil {
+			req.Tags = map[string]string{}
+		}
+		req.Tags[key] = value
+	}
+
+	times := map[string]**time.Time{
+		"first_seen_from": &req.FirstSeenFrom,
+		"first_seen_to":   &req.FirstSeenTo,
+		"last_seen_from":  &req.LastSeenFrom,
+		"last_seen_to":    &req.LastSeenTo,
+	}
+	for name, dst := range times {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return req, fmt.Errorf("%s must be an RFC 3339 time", name)
+			}
+			*dst = &t
+		}
+	}
+
+	ints := map[string]*int{
+		"min_count": &req.MinCount,
+		"limit":     &req.Limit,
+	}
+	for name, dst := range ints {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return req, fmt.Errorf("%s must be an integer", name)
+			}
+			*dst = n
+		}
+	}
+
+	return req, nil
 }
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dorianneto/bugfy/internal/api/model"
+	"github.com/dorianneto/bugfy/util"
+)
+
+const (
+	// maxSentryBodySize bounds the decompressed body of Sentry requests.
+	maxSentryBodySize = 20 << 20
+	// maxSentryEventSize bounds a single event, as Sentry itself does, so
+	// stored events stay well below MongoDB's document size limit.
+	maxSentryEventSize = 1 << 20
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// StoreSentryEvent accepts an event from a Sentry SDK, so SDKs can report
+// to Bugfy by pointing their DSN at it.
+func (h *ErrorHandler) StoreSentryEvent(w http.ResponseWriter, r *http.Request) {
+	projectID, _ := r.Context().Value("projectID").(string)
+
+	log.Printf("StoreSentryEvent - Request received: projectID=%s", projectID)
+
+	body, err := readSentryBody(r)
+	if err != nil {
+		log.Printf("StoreSentryEvent - Read error: %v", err)
+		util.WriteError(w, sentryBodyStatus(err), err.Error())
+		return
+	}
+	if len(body) > maxSentryEventSize {
+		log.Printf("StoreSentryEvent - Event too large: %d bytes", len(body))
+		util.WriteError(w, http.StatusRequestEntityTooLarge, errBodyTooLarge.Error())
+		return
+	}
+
+	var event model.SentryEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		log.Printf("StoreSentryEvent - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+
+	res, err := h.errorService.CreateSentryEvent(r.Context(), projectID, event)
+	if err != nil {
+		log.Printf("StoreSentryEvent - Service error: %v", err)
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	log.Printf("StoreSentryEvent - Success: event stored with ID=%s, projectID=%s", res.ID, projectID)
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+// StoreSentryEnvelope accepts an envelope from a Sentry SDK. Event items
+// are stored; other items, such as sessions and transactions, are ignored.
+// As in Sentry, an event that can't be stored is logged and skipped rather
+// than failing the envelope: the SDK would resend the whole envelope and
+// the events already stored would be counted twice. Only a failure before
+// any event is stored is reported, so the SDK retries it.
+func (h *ErrorHandler) StoreSentryEnvelope(w http.ResponseWriter, r *http.Request) {
+	projectID, _ := r.Context().Value("projectID").(string)
+
+	log.Printf("StoreSentryEnvelope - Request received: projectID=%s", projectID)
+
+	body, err := readSentryBody(r)
+	if err != nil {
+		log.Printf("StoreSentryEnvelope - Read error: %v", err)
+		util.WriteError(w, sentryBodyStatus(err), err.Error())
+		return
+	}
+
+	events, err := parseEnvelope(body)
+	if err != nil {
+		log.Printf("StoreSentryEnvelope - Envelope parse error: %v", err)
+		util.WriteError(w, sentryBodyStatus(err), "invalid envelope")
+		return
+	}
+
+	var res *model.ResponseSentryEvent
+
+	for _, event := range events {
+		stored, err := h.errorService.CreateSentryEvent(r.Context(), projectID, event)
+		if err != nil && res == nil && errorStatus(err, http.StatusInternalServerError) == http.StatusInternalServerError {
+			log.Printf("StoreSentryEnvelope - Service error: %v", err)
+			util.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("StoreSentryEnvelope - Skipping event %s: %v", event.EventID, err)
+			continue
+		}
+
+		log.Printf("StoreSentryEnvelope - Success: event stored with ID=%s, projectID=%s", stored.ID, projectID)
+		res = stored
+	}
+
+	if res == nil {
+		res = &model.ResponseSentryEvent{}
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+// readSentryBody reads the request body, decompressing it according to its
+// Content-Encoding.
+func readSentryBody(r *http.Request) ([]byte, error) {
+	var reader io.Reader = r.Body
+
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %v", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		zr, err := zlib.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate body: %v", err)
+		}
+		defer zr.Close()
+		reader = zr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxSentryBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %v", err)
+	}
+	if len(body) > maxSentryBodySize {
+		return nil, errBodyTooLarge
+	}
+
+	return body, nil
+}
+
+func sentryBodyStatus(err error) int {
+	if errors.Is(err, errBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// parseEnvelope returns the events of a Sentry envelope: a JSON header line
+// followed by items, each made of a JSON header line and a payload. The
+// payload is the header's "length" bytes, or runs to the end of the line
+// when the length is not given.
+func parseEnvelope(body []byte) ([]model.SentryEvent, error) {
+	_, rest, _ := bytes.Cut(body, []byte("\n"))
+
+	var events []model.SentryEvent
+
+	for len(bytes.TrimSpace(rest)) > 0 {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var header struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			return nil, fmt.Errorf("invalid item header: %v", err)
+		}
+
+		var payload []byte
+		if header.Length != nil {
+			if *header.Length < 0 || *header.Length > len(rest) {
+				return nil, fmt.Errorf("item length %d exceeds the envelope", *header.Length)
+			}
+			payload, rest = rest[:*header.Length], rest[*header.Length:]
+			rest = bytes.TrimPrefix(rest, []byte("\n"))
+		} else {
+			payload, rest, _ = bytes.Cut(rest, []byte("\n"))
+		}
+
+		if header.Type != "event" {
+			continue
+		}
+		if len(payload) > maxSentryEventSize {
+			return nil, fmt.Errorf("%w: event item of %d bytes", errBodyTooLarge, len(payload))
+		}
+
+		var event model.SentryEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid event item: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
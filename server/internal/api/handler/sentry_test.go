@@ -0,0 +1,115 @@
+package handler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dorianneto/bugfy/db"
+	"github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TestStoreSentryEnvelope posts an envelope the way sentry-python sends it,
+// to the URL it derives from the project's DSN, and checks the event is
+// grouped into an issue and its event ID returned. An event Bugfy rejects
+// is skipped without failing the envelope. It runs against the database at MONGODB_URI.
+func TestStoreSentryEnvelope(t *testing.T) {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+
+	client, err := db.NewDatabase()
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	server, projectID, publicKey := newTestServer(t, client)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project, err := repo.NewProjectRepository(client).GetProjectByID(ctx, projectID)
+	if err != nil || project == nil {
+		t.Fatalf("failed to fetch project: %v", err)
+	}
+
+	t.Setenv("PUBLIC_URL", server.URL)
+	dsn, err := url.Parse(util.BuildDSN(publicKey, strconv.FormatInt(project.SentryID, 10)))
+	if err != nil {
+		t.Fatalf("invalid DSN: %v", err)
+	}
+
+	// Sentry SDKs require the DSN's project ID to be a number.
+	dsnProjectID := strings.TrimPrefix(dsn.Path, "/")
+	if _, err := strconv.Atoi(dsnProjectID); err != nil {
+		t.Fatalf("DSN project ID %q is not numeric", dsnProjectID)
+	}
+
+	event := `{"event_id":"9ec79c33ec9942ab8353589fcb2e04dc","level":"error","platform":"python",` +
+		`"environment":"prod.eu","tags":{"server_name":"web-1"},` +
+		`"exception":{"values":[{"type":"ZeroDivisionError","value":"division by zero",` +
+		`"stacktrace":{"frames":[{"function":"main","filename":"app.py","lineno":3,"in_app":true}]}}]},` +
+		`"breadcrumbs":{"values":[{"timestamp":1700000000.25,"category":"query","level":"info","message":"SELECT 1"}]}}`
+	rejected := fmt.Sprintf(`{"event_id":"5b9c2a4e1f3d4c6b8a7e9d0c1b2a3f4e","release":"%s"}`, strings.Repeat("1", 201))
+	envelope := fmt.Sprintf("{\"event_id\":\"9ec79c33ec9942ab8353589fcb2e04dc\"}\n"+
+		"{\"type\":\"session\"}\n{\"status\":\"ok\"}\n"+
+		"{\"type\":\"event\"}\n%s\n"+
+		"{\"type\":\"event\",\"length\":%d}\n%s\n", rejected, len(event), event)
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if _, err := gz.Write([]byte(envelope)); err != nil {
+		t.Fatalf("failed to compress envelope: %v", err)
+	}
+	gz.Close()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/%s/envelope/", server.URL, dsnProjectID), &body)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=sentry.python/2.0.0, sentry_key="+publicKey)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, res.StatusCode)
+	}
+
+	var stored model.ResponseSentryEvent
+	if err := json.NewDecoder(res.Body).Decode(&stored); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if stored.ID != "9ec79c33ec9942ab8353589fcb2e04dc" {
+		t.Fatalf("expected the event's ID, got %q", stored.ID)
+	}
+
+	var issue repo.Issue
+	err = client.Database("portobello").Collection(repo.ISSUE_COLLECTION).
+		FindOne(ctx, bson.D{{Key: "project_id", Value: projectID}}).Decode(&issue)
+	if err != nil {
+		t.Fatalf("failed to fetch issue: %v", err)
+	}
+	if issue.Count != 1 || issue.ExceptionType != "ZeroDivisionError" {
+		t.Fatalf("unexpected issue: count=%d, exception_type=%q", issue.Count, issue.ExceptionType)
+	}
+}
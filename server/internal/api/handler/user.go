@@ -33,7 +33,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	user, err := h.userService.CreateUser(r.Context(), req)
 	if err != nil {
 		log.Printf("CreateUser - Service error: %v", err)
-		util.WriteError(w, http.StatusInternalServerError, err.Error())
+		util.WriteError(w, errorStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
@@ -53,46 +53,70 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	util.WriteJSON(w, http.StatusCreated, user)
 }
 
-// func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
-// 	var req model.RequestLoginUser
-// 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-// 		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
-// 		return
-// 	}
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req model.RequestLoginUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Login - JSON decode error: %v", err)
+		util.WriteError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
 
-// 	user, err := h.userService.Login(r.Context(), req)
-// 	if err != nil {
-// 		util.WriteError(w, http.StatusUnauthorized, err.Error())
-// 		return
-// 	}
+	log.Printf("Login - Request received: email=%s", req.Email)
 
-// 	// Set JWT cookie
-// 	http.SetCookie(w, &http.Cookie{
-// 		Name:     "jwt",
-// 		Value:    user.AccessToken,
-// 		Path:     "/",
-// 		MaxAge:   60 * 60 * 24,
-// 		HttpOnly: true,
-// 		Secure:   false,
-// 		SameSite: http.SameSiteLaxMode,
-// 	})
+	user, err := h.userService.Login(r.Context(), req)
+	if err != nil {
+		log.Printf("Login - Service error: %v", err)
+		util.WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
 
-// 	util.WriteJSON(w, http.StatusOK, user)
-// }
+	log.Printf("Login - Success: user logged in with ID=%s", user.ID)
 
-// func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
-// 	http.SetCookie(w, &http.Cookie{
-// 		Name:     "jwt",
-// 		Value:    "",
-// 		Path:     "/",
-// 		MaxAge:   -1,
-// 		HttpOnly: true,
-// 		Secure:   false,
-// 		SameSite: http.SameSiteLaxMode,
-// 	})
-
-// 	util.WriteJSON(w, http.StatusOK, map[string]string{"message": "logout successful"})
-// }
+	// Set JWT cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     "jwt",
+		Value:    user.AccessToken,
+		Path:     "/",
+		MaxAge:   60 * 60 * 24,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	util.WriteJSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "jwt",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	util.WriteJSON(w, http.StatusOK, map[string]string{"message": "logout successful"})
+}
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by JWT middleware)
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	user, err := h.userService.GetUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Me - Service error: %v", err)
+		util.WriteError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, user)
+}
 
 // func (h *UserHandler) UpdateUsername(w http.ResponseWriter, r *http.Request) {
 // 	// Get user ID from context (set by JWT middleware)
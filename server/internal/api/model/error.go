@@ -2,18 +2,174 @@ package model
 
 import "time"
 
+const (
+	LevelDebug   = "debug"
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+	LevelFatal   = "fatal"
+)
+
+var levels = []string{LevelDebug, LevelInfo, LevelWarning, LevelError, LevelFatal}
+
+// LevelRank orders levels by severity. Unknown levels rank 0.
+func LevelRank(level string) int {
+	for i, l := range levels {
+		if l == level {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// LevelForRank is the inverse of LevelRank.
+func LevelForRank(rank int) string {
+	if rank < 1 || rank > len(levels) {
+		return ""
+	}
+
+	return levels[rank-1]
+}
+
+type Exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// EventUser identifies the user affected by an event.
+type EventUser struct {
+	ID        string `json:"id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// EventRequest is the HTTP request being handled when an event occurred.
+type EventRequest struct {
+	URL     string            `json:"url,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Query   map[string]string `json:"query,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+type RuntimeContext struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type OSContext struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Build   string `json:"build,omitempty"`
+}
+
+type DeviceContext struct {
+	Family string `json:"family,omitempty"`
+	Model  string `json:"model,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+}
+
+// Breadcrumb is an event, such as a log line or navigation, recorded by
+// the SDK before an error occurred.
+type Breadcrumb struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Category  string         `json:"category,omitempty"`
+	Level     string         `json:"level,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// StackFrame is a single frame of a stack trace, ordered from the
+// outermost call to the frame where the error was raised.
+type StackFrame struct {
+	Function    string   `json:"function"`
+	Module      string   `json:"module,omitempty"`
+	Filename    string   `json:"filename"`
+	Line        int      `json:"line"`
+	Column      int      `json:"column,omitempty"`
+	InApp       bool     `json:"in_app"`
+	PreContext  []string `json:"pre_context,omitempty"`
+	ContextLine string   `json:"context_line,omitempty"`
+	PostContext []string `json:"post_context,omitempty"`
+}
+
 type RequestCreateError struct {
-	ProjectID string            `json:"project_id"`
-	Message   string            `json:"message"`
-	Context   map[string]string `json:"context"`
+	ProjectID  string            `json:"project_id"`
+	Message    string            `json:"message"`
+	StackTrace []StackFrame      `json:"stack_trace"`
+	Context    map[string]string `json:"context"`
+	// Release is the version of the application that raised the error.
+	Release string `json:"release"`
+	// Environment such as production or staging. It defaults to the
+	// "environment" context value.
+	Environment string `json:"environment"`
+	// Tags are indexed key/value pairs, such as browser or server_name,
+	// that issues can be filtered by.
+	Tags map[string]string `json:"tags"`
+	// Level defaults to error.
+	Level     string     `json:"level"`
+	Exception *Exception `json:"exception"`
+	// The typed context blocks describe the user, request and platform
+	// of the event; Extra holds any other data, which may be nested.
+	User    *EventUser      `json:"user"`
+	Request *EventRequest   `json:"request"`
+	Runtime *RuntimeContext `json:"runtime"`
+	OS      *OSContext      `json:"os"`
+	Device  *DeviceContext  `json:"device"`
+	Extra   map[string]any  `json:"extra"`
+	// Breadcrumbs are ordered from oldest to newest. Only the most recent
+	// ones are kept when there are too many.
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs"`
+	// Fingerprint overrides the server-computed grouping key. The
+	// "{{ default }}" placeholder expands to that key.
+	Fingerprint []string `json:"fingerprint"`
 }
 
 type ResponseCreateError struct {
-	ID          string            `json:"id"`
-	ProjectID   string            `json:"project_id"`
-	Message     string            `json:"message"`
-	Type        string            `json:"type"`
-	Fingerprint string            `json:"fingerprint"`
-	Context     map[string]string `json:"context"`
-	Timestamp   time.Time         `json:"timestamp"`
+	ID                 string            `json:"id"`
+	ProjectID          string            `json:"project_id"`
+	Message            string            `json:"message"`
+	Type               string            `json:"type"`
+	Fingerprint        string            `json:"fingerprint"`
+	FingerprintVersion int               `json:"fingerprint_version"`
+	StackTrace         []StackFrame      `json:"stack_trace"`
+	Context            map[string]string `json:"context"`
+	Release            string            `json:"release,omitempty"`
+	Environment        string            `json:"environment,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	Level              string            `json:"level"`
+	Exception          *Exception        `json:"exception,omitempty"`
+	Timestamp          time.Time         `json:"timestamp"`
+}
+
+type ResponseEvent struct {
+	ID                 string            `json:"id"`
+	ProjectID          string            `json:"project_id"`
+	IssueID            string            `json:"issue_id"`
+	Message            string            `json:"message"`
+	Type               string            `json:"type"`
+	Fingerprint        string            `json:"fingerprint"`
+	FingerprintVersion int               `json:"fingerprint_version"`
+	StackTrace         []StackFrame      `json:"stack_trace"`
+	Context            map[string]string `json:"context"`
+	Release            string            `json:"release,omitempty"`
+	Environment        string            `json:"environment,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	Level              string            `json:"level"`
+	Exception          *Exception        `json:"exception,omitempty"`
+	User               *EventUser        `json:"user,omitempty"`
+	Request            *EventRequest     `json:"request,omitempty"`
+	Runtime            *RuntimeContext   `json:"runtime,omitempty"`
+	OS                 *OSContext        `json:"os,omitempty"`
+	Device             *DeviceContext    `json:"device,omitempty"`
+	Extra              map[string]any    `json:"extra,omitempty"`
+	Breadcrumbs        []Breadcrumb      `json:"breadcrumbs,omitempty"`
+	Timestamp          time.Time         `json:"timestamp"`
+}
+
+type RequestGetEvents struct {
+	Cursor string
+	Limit  int
 }
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -12,13 +13,164 @@ const (
 	IssueStateIgnored
 )
 
+func (s IssueState) String() string {
+	switch s {
+	case IssueStateUnresolved:
+		return "unresolved"
+	case IssueStateResolved:
+		return "resolved"
+	case IssueStateIgnored:
+		return "ignored"
+	}
+
+	return fmt.Sprintf("IssueState(%d)", int(s))
+}
+
+func ParseIssueState(s string) (IssueState, error) {
+	switch s {
+	case "unresolved":
+		return IssueStateUnresolved, nil
+	case "resolved":
+		return IssueStateResolved, nil
+	case "ignored":
+		return IssueStateIgnored, nil
+	}
+
+	return 0, fmt.Errorf("unknown issue status %q", s)
+}
+
+const (
+	ActivitySetResolved   = "set_resolved"
+	ActivitySetUnresolved = "set_unresolved"
+	ActivitySetIgnored    = "set_ignored"
+	ActivitySetRegression = "set_regression"
+	ActivitySetUnignored  = "set_unignored"
+	ActivityMerge         = "merge"
+	ActivityUnmerge       = "unmerge"
+)
+
+// ActivityForState returns the activity type recorded when an issue is
+// moved to s.
+func ActivityForState(s IssueState) string {
+	switch s {
+	case IssueStateResolved:
+		return ActivitySetResolved
+	case IssueStateIgnored:
+		return ActivitySetIgnored
+	}
+
+	return ActivitySetUnresolved
+}
+
+const (
+	IssueSortLastSeen  = "last_seen"
+	IssueSortFirstSeen = "first_seen"
+	IssueSortCount     = "count"
+)
+
+// RequestGetIssues holds the query parameters of an issue listing. Empty
+// fields don't filter; the date bounds are inclusive.
+type RequestGetIssues struct {
+	Status        string
+	FirstSeenFrom *time.Time
+	FirstSeenTo   *time.Time
+	LastSeenFrom  *time.Time
+	LastSeenTo    *time.Time
+	MinCount      int
+	Environment   string
+	Release       string
+	Level         string
+	// Tags filters on the tags of the issue's events.
+	Tags map[string]string
+	// Query is a search such as `is:unresolved release:1.4.2 "timeout"`.
+	Query  string
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+type RequestUpdateIssue struct {
+	Status string `json:"status"`
+	// The ignore conditions are only valid with the ignored status. The
+	// issue is unignored after IgnoreDuration minutes, after IgnoreCount
+	// more events or, with IgnoreWindow set, once IgnoreCount events occur
+	// within IgnoreWindow minutes.
+	IgnoreDuration int `json:"ignore_duration"`
+	IgnoreCount    int `json:"ignore_count"`
+	IgnoreWindow   int `json:"ignore_window"`
+	// ResolvedInRelease is only valid with the resolved status. Events from
+	// older releases then don't reopen the issue.
+	ResolvedInRelease string `json:"resolved_in_release"`
+}
+
+type RequestBulkUpdateIssues struct {
+	IDs []string `json:"ids"`
+	RequestUpdateIssue
+}
+
+type RequestMergeIssues struct {
+	PrimaryID string   `json:"primary_id"`
+	IDs       []string `json:"ids"`
+}
+
+type RequestUnmergeIssue struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+type ResponseIgnoreCondition struct {
+	Until     *time.Time `json:"until,omitempty"`
+	Count     int        `json:"count,omitempty"`
+	Window    int        `json:"window,omitempty"`
+	IgnoredAt time.Time  `json:"ignored_at"`
+}
+
+type ResponseEnvironmentStats struct {
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type ResponseTagValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type ResponseTagDistribution struct {
+	Key       string             `json:"key"`
+	Total     int                `json:"total"`
+	Unique    int                `json:"unique"`
+	TopValues []ResponseTagValue `json:"top_values"`
+}
+
+type ResponseIssueActivity struct {
+	ID        string            `json:"id"`
+	IssueID   string            `json:"issue_id"`
+	UserID    string            `json:"user_id,omitempty"`
+	Type      string            `json:"type"`
+	Data      map[string]string `json:"data,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
 type ResponseGetIssues struct {
-	ID          string     `json:"id"`
-	ProjectID   string     `json:"project_id"`
-	Title       string     `json:"title"`
-	Fingerprint string     `json:"fingerprint"`
-	Count       int        `json:"count"`
-	FirstSeen   time.Time  `json:"first_seen"`
-	LastSeen    time.Time  `json:"last_seen"`
-	Status      IssueState `json:"status"`
+	ID                 string                              `json:"id"`
+	ProjectID          string                              `json:"project_id"`
+	Title              string                              `json:"title"`
+	Fingerprint        string                              `json:"fingerprint"`
+	FingerprintVersion int                                 `json:"fingerprint_version"`
+	Count              int                                 `json:"count"`
+	FirstSeen          time.Time                           `json:"first_seen"`
+	LastSeen           time.Time                           `json:"last_seen"`
+	Status             IssueState                          `json:"status"`
+	StatusChangedBy    string                              `json:"status_changed_by,omitempty"`
+	StatusChangedAt    *time.Time                          `json:"status_changed_at,omitempty"`
+	Ignore             *ResponseIgnoreCondition            `json:"ignore,omitempty"`
+	Regressed          bool                                `json:"regressed"`
+	RegressedAt        *time.Time                          `json:"regressed_at,omitempty"`
+	RegressionEventID  string                              `json:"regression_event_id,omitempty"`
+	ResolvedInRelease  string                              `json:"resolved_in_release,omitempty"`
+	MergedFingerprints []string                            `json:"merged_fingerprints,omitempty"`
+	UserCount          int                                 `json:"user_count"`
+	Level              string                              `json:"level"`
+	Environments       map[string]ResponseEnvironmentStats `json:"environments,omitempty"`
+	ExceptionType      string                              `json:"exception_type,omitempty"`
 }
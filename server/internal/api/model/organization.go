@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// RoleRank orders roles by privilege. Unknown roles rank below member.
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleMember:
+		return 1
+	}
+
+	return 0
+}
+
+type RequestCreateOrganization struct {
+	Name string `json:"name"`
+}
+
+type ResponseOrganization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseMember struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RequestUpdateMember struct {
+	Role string `json:"role"`
+}
+
+type RequestCreateInvite struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type ResponseInvite struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	Token          string    `json:"token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
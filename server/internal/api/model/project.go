@@ -2,13 +2,63 @@ package model
 
 import "time"
 
+const (
+	GroupingMatcherMessage = "message"
+	GroupingMatcherContext = "context"
+	GroupingMatcherModule  = "module"
+)
+
+// GroupingRule assigns a fingerprint to every event it matches. Message and
+// module rules match Pattern as a regular expression; context rules match
+// when Key is present and, if set, its value matches Pattern.
+type GroupingRule struct {
+	Matcher     string   `json:"matcher"`
+	Key         string   `json:"key,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Fingerprint []string `json:"fingerprint"`
+}
+
+type RequestUpdateGroupingRules struct {
+	Rules []GroupingRule `json:"rules"`
+}
+
+type ResponseGroupingRules struct {
+	ProjectID string         `json:"project_id"`
+	Rules     []GroupingRule `json:"rules"`
+}
+
+type RequestCreateProjectKey struct {
+	Label string `json:"label"`
+	// RateLimit caps the events accepted per minute with this key; zero
+	// means unlimited.
+	RateLimit  int  `json:"rate_limit"`
+	WithSecret bool `json:"with_secret"`
+}
+
+type ResponseProjectKey struct {
+	ID         string     `json:"id"`
+	Label      string     `json:"label"`
+	PublicKey  string     `json:"public_key"`
+	SecretKey  string     `json:"secret_key,omitempty"`
+	DSN        string     `json:"dsn"`
+	RateLimit  int        `json:"rate_limit"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
 type RequestCreateProject struct {
-	Title string `json:"title"`
+	Title          string `json:"title"`
+	OrganizationID string `json:"organization_id"`
 }
 
 type ResponseCreateProject struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	Title          string    `json:"title"`
+	OrganizationID string    `json:"organization_id"`
+	OwnerID        string    `json:"owner_id"`
+	DSN            string    `json:"dsn"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
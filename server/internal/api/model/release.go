@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type RequestCreateRelease struct {
+	Version string `json:"version"`
+}
+
+type ResponseRelease struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Version   string    `json:"version"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
@@ -0,0 +1,267 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SentryEvent is an event as sent by Sentry SDKs to the store endpoint or
+// as an "event" envelope item. Only the attributes Bugfy stores are
+// decoded; fields Sentry accepts in several shapes use the types below.
+type SentryEvent struct {
+	EventID     string                         `json:"event_id"`
+	Message     SentryMessage                  `json:"message"`
+	LogEntry    *SentryLogEntry                `json:"logentry"`
+	Level       string                         `json:"level"`
+	Platform    string                         `json:"platform"`
+	Logger      string                         `json:"logger"`
+	ServerName  string                         `json:"server_name"`
+	Transaction string                         `json:"transaction"`
+	Release     string                         `json:"release"`
+	Environment string                         `json:"environment"`
+	Tags        SentryMap                      `json:"tags"`
+	Extra       map[string]any                 `json:"extra"`
+	User        *SentryUser                    `json:"user"`
+	Request     *SentryRequest                 `json:"request"`
+	Contexts    SentryContexts                 `json:"contexts"`
+	Exception   SentryValues[SentryException]  `json:"exception"`
+	Stacktrace  *SentryStacktrace              `json:"stacktrace"`
+	Breadcrumbs SentryValues[SentryBreadcrumb] `json:"breadcrumbs"`
+	Fingerprint []string                       `json:"fingerprint"`
+}
+
+type SentryLogEntry struct {
+	Message   string `json:"message"`
+	Formatted string `json:"formatted"`
+}
+
+type SentryUser struct {
+	ID        SentryString `json:"id"`
+	Email     string       `json:"email"`
+	IPAddress string       `json:"ip_address"`
+	Username  string       `json:"username"`
+}
+
+type SentryRequest struct {
+	URL         string    `json:"url"`
+	Method      string    `json:"method"`
+	Headers     SentryMap `json:"headers"`
+	QueryString SentryMap `json:"query_string"`
+	Data        any       `json:"data"`
+}
+
+type SentryContexts struct {
+	Runtime *RuntimeContext `json:"runtime"`
+	OS      *OSContext      `json:"os"`
+	Device  *DeviceContext  `json:"device"`
+}
+
+type SentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Module     string            `json:"module"`
+	Stacktrace *SentryStacktrace `json:"stacktrace"`
+}
+
+type SentryStacktrace struct {
+	Frames []SentryFrame `json:"frames"`
+}
+
+// SentryFrame is a stack frame. Like StackFrame, Sentry orders frames from
+// the outermost call to the one where the error was raised.
+type SentryFrame struct {
+	Function    string   `json:"function"`
+	Module      string   `json:"module"`
+	Filename    string   `json:"filename"`
+	AbsPath     string   `json:"abs_path"`
+	Lineno      int      `json:"lineno"`
+	Colno       int      `json:"colno"`
+	InApp       bool     `json:"in_app"`
+	PreContext  []string `json:"pre_context"`
+	ContextLine string   `json:"context_line"`
+	PostContext []string `json:"post_context"`
+}
+
+type SentryBreadcrumb struct {
+	Timestamp SentryTime     `json:"timestamp"`
+	Type      string         `json:"type"`
+	Category  string         `json:"category"`
+	Level     string         `json:"level"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data"`
+}
+
+// SentryMessage is a message sent either as a string or as an object with
+// "formatted" and "message" attributes.
+type SentryMessage string
+
+func (m *SentryMessage) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte("{")) {
+		var e SentryLogEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		*m = SentryMessage(e.Formatted)
+		if e.Formatted == "" {
+			*m = SentryMessage(e.Message)
+		}
+		return nil
+	}
+
+	var s SentryString
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*m = SentryMessage(s)
+
+	return nil
+}
+
+// SentryString is a string that may be sent as a JSON number or boolean,
+// such as a numeric user ID.
+type SentryString string
+
+func (s *SentryString) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = SentryString(v)
+	case float64, bool:
+		*s = SentryString(fmt.Sprint(v))
+	default:
+		return fmt.Errorf("expected a string, got %s", data)
+	}
+
+	return nil
+}
+
+// SentryMap is a string map sent either as an object or as a list of
+// [key, value] pairs. A string is parsed as a URL query, which is how
+// request query strings may be sent.
+type SentryMap map[string]string
+
+func (m *SentryMap) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	result := SentryMap{}
+
+	switch v := v.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case string:
+		query, err := url.ParseQuery(v)
+		if err != nil {
+			return err
+		}
+		for k := range query {
+			result[k] = query.Get(k)
+		}
+	case map[string]any:
+		for k, value := range v {
+			if value != nil {
+				result[k] = fmt.Sprint(value)
+			}
+		}
+	case []any:
+		for _, pair := range v {
+			p, ok := pair.([]any)
+			if !ok || len(p) != 2 || p[1] == nil {
+				return fmt.Errorf("expected a [key, value] pair, got %v", pair)
+			}
+			result[fmt.Sprint(p[0])] = fmt.Sprint(p[1])
+		}
+	default:
+		return fmt.Errorf("expected an object or a list of pairs, got %s", data)
+	}
+
+	*m = result
+
+	return nil
+}
+
+// SentryValues is a list sent either as is or wrapped in an object's
+// "values" attribute.
+type SentryValues[T any] []T
+
+func (v *SentryValues[T]) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte("{")) {
+		var wrapped struct {
+			Values []T `json:"values"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return err
+		}
+		*v = wrapped.Values
+		return nil
+	}
+
+	var values []T
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*v = values
+
+	return nil
+}
+
+// SentryTime is a timestamp sent either as an RFC 3339 string or as a
+// number of seconds since the Unix epoch.
+type SentryTime time.Time
+
+func (t *SentryTime) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		*t = SentryTime{}
+	case float64:
+		*t = SentryTime(unixTime(v))
+	case string:
+		if sec, err := strconv.ParseFloat(v, 64); err == nil {
+			*t = SentryTime(unixTime(sec))
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			// SDKs may leave out the time zone, which is then UTC.
+			parsed, err = time.Parse("2006-01-02T15:04:05.999999999", v)
+			if err != nil {
+				return fmt.Errorf("invalid timestamp %q", v)
+			}
+		}
+		*t = SentryTime(parsed)
+	default:
+		return fmt.Errorf("expected a timestamp, got %s", data)
+	}
+
+	return nil
+}
+
+func unixTime(seconds float64) time.Time {
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// ResponseSentryEvent is the response Sentry SDKs expect once an event is
+// stored.
+type ResponseSentryEvent struct {
+	ID string `json:"id,omitempty"`
+}
@@ -1,9 +1,12 @@
 package model
 
+import "time"
+
 type RequestCreateUser struct {
-	Username string
-	Email    string
-	Password string
+	Username    string
+	Email       string
+	Password    string
+	InviteToken string `json:"invite_token"`
 }
 
 type RequestLoginUser struct {
@@ -11,6 +14,13 @@ type RequestLoginUser struct {
 	Password string `json:"password"`
 }
 
+type ResponseGetUser struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type ResponseLoginUser struct {
 	AccessToken string
 	ID          string `json:"id"`
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const ACTIVITY_COLLECTION = "issue_activities"
+
+type Activity struct {
+	ID        bson.ObjectID     `bson:"_id,omitempty"`
+	IssueID   bson.ObjectID     `bson:"issue_id"`
+	ProjectID bson.ObjectID     `bson:"project_id"`
+	UserID    bson.ObjectID     `bson:"user_id,omitempty"`
+	Type      string            `bson:"type"`
+	Data      map[string]string `bson:"data,omitempty"`
+	CreatedAt time.Time         `bson:"created_at"`
+}
+
+type ActivityRepository struct {
+	db *mongo.Client
+}
+
+func NewActivityRepository(db *mongo.Client) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+func (r *ActivityRepository) EnsureIndexes(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(ACTIVITY_COLLECTION)
+
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "issue_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create activity indexes: %s", err)
+	}
+
+	return nil
+}
+
+func (r *ActivityRepository) CreateActivity(ctx context.Context, a *Activity) (*Activity, error) {
+	coll := r.db.Database("portobello").Collection(ACTIVITY_COLLECTION)
+
+	result, err := coll.InsertOne(ctx, a)
+	if err != nil {
+		return nil, fmt.Errorf("insert activity: %s", err)
+	}
+
+	a.ID = result.InsertedID.(bson.ObjectID)
+
+	return a, nil
+}
+
+func (r *ActivityRepository) FindActivitiesByIssue(ctx context.Context, issueID bson.ObjectID) ([]Activity, error) {
+	coll := r.db.Database("portobello").Collection(ACTIVITY_COLLECTION)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	result, err := coll.Find(ctx, bson.D{{Key: "issue_id", Value: issueID}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch activities: %s", err)
+	}
+
+	var a []Activity
+
+	err = result.All(ctx, &a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode activities: %s", err)
+	}
+
+	return a, nil
+}
@@ -5,21 +5,148 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dorianneto/bugfy/internal/api/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 const ERROR_COLLECTION = "errors"
 
-// TODO: Add stack trace to error struct
+type StackFrame struct {
+	Function    string   `bson:"function,omitempty"`
+	Module      string   `bson:"module,omitempty"`
+	Filename    string   `bson:"filename,omitempty"`
+	Line        int      `bson:"line,omitempty"`
+	Column      int      `bson:"column,omitempty"`
+	InApp       bool     `bson:"in_app"`
+	PreContext  []string `bson:"pre_context,omitempty"`
+	ContextLine string   `bson:"context_line,omitempty"`
+	PostContext []string `bson:"post_context,omitempty"`
+}
+
+type Exception struct {
+	Type  string `bson:"type,omitempty"`
+	Value string `bson:"value,omitempty"`
+}
+
+type EventUser struct {
+	ID        string `bson:"id,omitempty"`
+	Email     string `bson:"email,omitempty"`
+	IPAddress string `bson:"ip_address,omitempty"`
+	Username  string `bson:"username,omitempty"`
+}
+
+type EventRequest struct {
+	URL     string            `bson:"url,omitempty"`
+	Method  string            `bson:"method,omitempty"`
+	Headers map[string]string `bson:"headers,omitempty"`
+	Query   map[string]string `bson:"query,omitempty"`
+	Body    any               `bson:"body,omitempty"`
+}
+
+type RuntimeContext struct {
+	Name    string `bson:"name,omitempty"`
+	Version string `bson:"version,omitempty"`
+}
+
+type OSContext struct {
+	Name    string `bson:"name,omitempty"`
+	Version string `bson:"version,omitempty"`
+	Build   string `bson:"build,omitempty"`
+}
+
+type DeviceContext struct {
+	Family string `bson:"family,omitempty"`
+	Model  string `bson:"model,omitempty"`
+	Arch   string `bson:"arch,omitempty"`
+}
+
+type Breadcrumb struct {
+	Timestamp time.Time `bson:"timestamp"`
+	Category  string    `bson:"category,omitempty"`
+	Level     string    `bson:"level,omitempty"`
+	Message   string    `bson:"message,omitempty"`
+	Data      bson.M    `bson:"data,omitempty"`
+}
+
+type Tag struct {
+	Key   string `bson:"key"`
+	Value string `bson:"value"`
+}
+
 type Error struct {
-	ID          bson.ObjectID     `bson:"_id,omitempty"`
-	ProjectID   bson.ObjectID     `bson:"project_id,omitempty"`
-	Message     string            `bson:"message,omitempty"`
-	Type        string            `bson:"type,omitempty"`
-	Fingerprint string            `bson:"fingerprint,omitempty"`
-	Context     map[string]string `bson:"context,omitempty"`
-	Timestamp   time.Time         `bson:"timestamp,omitempty"`
+	ID                 bson.ObjectID     `bson:"_id,omitempty"`
+	ProjectID          bson.ObjectID     `bson:"project_id,omitempty"`
+	IssueID            bson.ObjectID     `bson:"issue_id,omitempty"`
+	Message            string            `bson:"message,omitempty"`
+	Type               string            `bson:"type,omitempty"`
+	Fingerprint        string            `bson:"fingerprint,omitempty"`
+	FingerprintVersion int               `bson:"fingerprint_version,omitempty"`
+	StackTrace         []StackFrame      `bson:"stack_trace,omitempty"`
+	Context            map[string]string `bson:"context,omitempty"`
+	Release            string            `bson:"release,omitempty"`
+	Environment        string            `bson:"environment,omitempty"`
+	// Tags are stored as key/value pairs so a single index covers them.
+	Tags      []Tag      `bson:"tags,omitempty"`
+	Level     string     `bson:"level,omitempty"`
+	Exception *Exception `bson:"exception,omitempty"`
+	User      *EventUser `bson:"user,omitempty"`
+	// UserKey identifies User for counting the users affected by an
+	// issue, preferring its ID over its email, username and IP address.
+	UserKey     string          `bson:"user_key,omitempty"`
+	Request     *EventRequest   `bson:"request,omitempty"`
+	Runtime     *RuntimeContext `bson:"runtime,omitempty"`
+	OS          *OSContext      `bson:"os,omitempty"`
+	Device      *DeviceContext  `bson:"device,omitempty"`
+	Extra       bson.M          `bson:"extra,omitempty"`
+	Breadcrumbs []Breadcrumb    `bson:"breadcrumbs,omitempty"`
+	Timestamp   time.Time       `bson:"timestamp,omitempty"`
+}
+
+// IssueStats are the statistics of an issue computed from its errors.
+type IssueStats struct {
+	Count        int       `bson:"count"`
+	FirstSeen    time.Time `bson:"first_seen"`
+	LastSeen     time.Time `bson:"last_seen"`
+	Environments []string  `bson:"-"`
+	Releases     []string  `bson:"releases"`
+	LevelRank    int       `bson:"-"`
+	Levels       []string  `bson:"levels"`
+
+	EnvironmentStats map[string]EnvironmentStats `bson:"-"`
+}
+
+func (s IssueStats) fields() bson.D {
+	return bson.D{
+		{Key: "count", Value: s.Count},
+		{Key: "first_seen", Value: s.FirstSeen},
+		{Key: "last_seen", Value: s.LastSeen},
+		{Key: "environments", Value: s.Environments},
+		{Key: "releases", Value: s.Releases},
+		{Key: "level_rank", Value: s.LevelRank},
+		{Key: "environment_stats", Value: s.EnvironmentStats},
+	}
+}
+
+type TagValue struct {
+	Value string `bson:"value"`
+	Count int    `bson:"count"`
+}
+
+// TagDistribution counts the events of an issue carrying a tag key, the
+// number of distinct values and the most common ones.
+type TagDistribution struct {
+	Key       string     `bson:"_id"`
+	Total     int        `bson:"total"`
+	Unique    int        `bson:"unique"`
+	TopValues []TagValue `bson:"top_values"`
+}
+
+// ErrorCursor is the position of the last error of a page.
+type ErrorCursor struct {
+	Timestamp time.Time
+	ID        bson.ObjectID
 }
 
 type ErrorRepository struct {
@@ -30,6 +157,27 @@ func NewErrorRepository(db *mongo.Client) *ErrorRepository {
 	return &ErrorRepository{db: db}
 }
 
+func (r *ErrorRepository) EnsureIndexes(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "issue_id", Value: 1}, {Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "tags.key", Value: 1}, {Key: "tags.value", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create error indexes: %s", err)
+	}
+
+	return nil
+}
+
 func (r *ErrorRepository) CreateError(ctx context.Context, e *Error) (*Error, error) {
 	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
 
@@ -53,3 +201,334 @@ func (r *ErrorRepository) DeleteError(ctx context.Context, id bson.ObjectID) err
 
 	return nil
 }
+
+// SetErrorIssue links an error to the issue it was grouped into.
+func (r *ErrorRepository) SetErrorIssue(ctx context.Context, id bson.ObjectID, issueID bson.ObjectID) error {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	_, err := coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{{Key: "$set", Value: bson.D{{Key: "issue_id", Value: issueID}}}})
+	if err != nil {
+		return fmt.Errorf("failed to link error to issue: %s", err)
+	}
+
+	return nil
+}
+
+// BackfillIssueIDs links the errors stored before errors referenced their
+// issue, matching them to issues by project and fingerprint. It runs on
+// every startup, so only unlinked errors are read: an equality match on
+// null is answered from the issue_id index, and once the old errors are
+// linked it finds nothing.
+func (r *ErrorRepository) BackfillIssueIDs(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "issue_id", Value: nil}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: ISSUE_COLLECTION},
+			{Key: "let", Value: bson.D{{Key: "project_id", Value: "$project_id"}, {Key: "fingerprint", Value: "$fingerprint"}}},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$eq", Value: bson.A{"$project_id", "$$project_id"}}},
+					bson.D{{Key: "$eq", Value: bson.A{"$fingerprint", "$$fingerprint"}}},
+				}}}}}}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "issue"},
+		}}},
+		{{Key: "$unwind", Value: "$issue"}},
+		{{Key: "$project", Value: bson.D{{Key: "issue_id", Value: "$issue._id"}}}},
+		{{Key: "$merge", Value: bson.D{
+			{Key: "into", Value: ERROR_COLLECTION},
+			{Key: "on", Value: "_id"},
+			{Key: "whenMatched", Value: "merge"},
+			{Key: "whenNotMatched", Value: "discard"},
+		}}},
+	}
+
+	result, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to backfill issue ids: %s", err)
+	}
+
+	return result.Close(ctx)
+}
+
+func (r *ErrorRepository) CountErrorsSince(ctx context.Context, issueID bson.ObjectID, since time.Time) (int64, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	filter := bson.D{
+		{Key: "issue_id", Value: issueID},
+		{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: since}}},
+	}
+
+	n, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count errors: %s", err)
+	}
+
+	return n, nil
+}
+
+// userFields are the fields of the user of an error that can be searched.
+var userFields = map[string]bool{
+	"user.id":         true,
+	"user.email":      true,
+	"user.username":   true,
+	"user.ip_address": true,
+}
+
+// FindIssueIDs returns the distinct issues of a project's errors that
+// have all the given tags and, for each of values, a tag, a context entry
+// or, for the user fields, a user with that value. Context keys may contain dots, so they are
+// matched with $getField rather than as paths, and both keys and values are
+// wrapped in $literal so a leading "$" is never read as a field path.
+func (r *ErrorRepository) FindIssueIDs(ctx context.Context, projectID bson.ObjectID, tags map[string]string, values map[string]string) ([]bson.ObjectID, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	conditions := bson.A{}
+	for k, v := range tags {
+		conditions = append(conditions, tagCondition(k, v))
+	}
+	for k, v := range values {
+		field := bson.D{{Key: "$getField", Value: bson.D{
+			{Key: "field", Value: bson.D{{Key: "$literal", Value: k}}},
+			{Key: "input", Value: "$context"},
+		}}}
+		alternatives := bson.A{
+			tagCondition(k, v),
+			bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{
+				field,
+				bson.D{{Key: "$literal", Value: v}},
+			}}}}},
+		}
+		if userFields[k] {
+			alternatives = append(alternatives, bson.D{{Key: k, Value: v}})
+		}
+		conditions = append(conditions, bson.D{{Key: "$or", Value: alternatives}})
+	}
+
+	filter := bson.D{
+		{Key: "project_id", Value: projectID},
+		{Key: "$and", Value: conditions},
+	}
+
+	var ids []bson.ObjectID
+
+	err := coll.Distinct(ctx, "issue_id", filter).Decode(&ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find issues: %s", err)
+	}
+
+	return ids, nil
+}
+
+func (r *ErrorRepository) GetErrorByID(ctx context.Context, id bson.ObjectID) (*Error, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	var e Error
+
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find error: %s", err)
+	}
+
+	return &e, nil
+}
+
+// FindErrorsByIssue returns up to limit errors of an issue, newest first.
+// With after set, only the errors following that cursor are returned.
+func (r *ErrorRepository) FindErrorsByIssue(ctx context.Context, issueID bson.ObjectID, after *ErrorCursor, limit int) ([]Error, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	filter := bson.D{{Key: "issue_id", Value: issueID}}
+	if after != nil {
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "timestamp", Value: bson.D{{Key: "$lt", Value: after.Timestamp}}}},
+			bson.D{{Key: "timestamp", Value: after.Timestamp}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: after.ID}}}},
+		}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	result, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch errors: %s", err)
+	}
+
+	var e []Error
+
+	err = result.All(ctx, &e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode errors: %s", err)
+	}
+
+	return e, nil
+}
+
+// FindEdgeError returns the oldest or, with newest set, the newest error
+// of an issue, or nil if there is none.
+func (r *ErrorRepository) FindEdgeError(ctx context.Context, issueID bson.ObjectID, newest bool) (*Error, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	order := 1
+	if newest {
+		order = -1
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: order}, {Key: "_id", Value: order}})
+
+	var e Error
+
+	err := coll.FindOne(ctx, bson.D{{Key: "issue_id", Value: issueID}}, opts).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find error: %s", err)
+	}
+
+	return &e, nil
+}
+
+// MoveErrors re-points the errors of the from issues to the to issue. With
+// fingerprint set, only the errors with that fingerprint are moved.
+func (r *ErrorRepository) MoveErrors(ctx context.Context, from []bson.ObjectID, fingerprint string, to bson.ObjectID) error {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	filter := bson.D{{Key: "issue_id", Value: bson.D{{Key: "$in", Value: from}}}}
+	if fingerprint != "" {
+		filter = append(filter, bson.E{Key: "fingerprint", Value: fingerprint})
+	}
+
+	_, err := coll.UpdateMany(ctx, filter, bson.D{{Key: "$set", Value: bson.D{{Key: "issue_id", Value: to}}}})
+	if err != nil {
+		return fmt.Errorf("failed to move errors: %s", err)
+	}
+
+	return nil
+}
+
+// ComputeIssueStats aggregates the statistics of an issue from its errors.
+// It returns nil if the issue has no errors.
+func (r *ErrorRepository) ComputeIssueStats(ctx context.Context, issueID bson.ObjectID) (*IssueStats, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "issue_id", Value: issueID}}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "overall", Value: bson.A{bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "first_seen", Value: bson.D{{Key: "$min", Value: "$timestamp"}}},
+				{Key: "last_seen", Value: bson.D{{Key: "$max", Value: "$timestamp"}}},
+				{Key: "releases", Value: bson.D{{Key: "$addToSet", Value: "$release"}}},
+				{Key: "levels", Value: bson.D{{Key: "$addToSet", Value: "$level"}}},
+			}}}}},
+			{Key: "environments", Value: bson.A{bson.D{{Key: "$group", Value: bson.D{
+				// Only the normalized field is used: a raw context environment
+				// may not be a valid key of environment_stats.
+				{Key: "_id", Value: "$environment"},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "first_seen", Value: bson.D{{Key: "$min", Value: "$timestamp"}}},
+				{Key: "last_seen", Value: bson.D{{Key: "$max", Value: "$timestamp"}}},
+			}}}}},
+		}}},
+	}
+
+	result, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute issue stats: %s", err)
+	}
+
+	var facets []struct {
+		Overall      []IssueStats `bson:"overall"`
+		Environments []struct {
+			Name             *string `bson:"_id"`
+			EnvironmentStats `bson:",inline"`
+		} `bson:"environments"`
+	}
+
+	err = result.All(ctx, &facets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode issue stats: %s", err)
+	}
+	if len(facets) == 0 || len(facets[0].Overall) == 0 {
+		return nil, nil
+	}
+
+	stats := facets[0].Overall[0]
+	stats.Environments = []string{}
+	stats.EnvironmentStats = map[string]EnvironmentStats{}
+
+	for _, level := range stats.Levels {
+		stats.LevelRank = max(stats.LevelRank, model.LevelRank(level))
+	}
+
+	for _, env := range facets[0].Environments {
+		if env.Name == nil || *env.Name == "" {
+			continue
+		}
+		stats.Environments = append(stats.Environments, *env.Name)
+		stats.EnvironmentStats[*env.Name] = env.EnvironmentStats
+	}
+
+	return &stats, nil
+}
+
+func tagCondition(key string, value string) bson.D {
+	return bson.D{{Key: "tags", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+		{Key: "key", Value: key},
+		{Key: "value", Value: value},
+	}}}}}
+}
+
+// FindTagDistribution returns the distribution of the tags of an issue's
+// errors, with up to top values per key, most common keys first.
+func (r *ErrorRepository) FindTagDistribution(ctx context.Context, issueID bson.ObjectID, top int) ([]TagDistribution, error) {
+	coll := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "issue_id", Value: issueID}}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "key", Value: "$tags.key"}, {Key: "value", Value: "$tags.value"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id.value", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$_id.key"},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+			{Key: "unique", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "values", Value: bson.D{{Key: "$push", Value: bson.D{
+				{Key: "value", Value: "$_id.value"},
+				{Key: "count", Value: "$count"},
+			}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "total", Value: 1},
+			{Key: "unique", Value: 1},
+			{Key: "top_values", Value: bson.D{{Key: "$slice", Value: bson.A{"$values", top}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	result, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate tags: %s", err)
+	}
+
+	var d []TagDistribution
+
+	err = result.All(ctx, &d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %s", err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,84 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dorianneto/bugfy/db"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// TestFindIssueIDsLiteralValue checks that a search value starting with "$"
+// is compared as a string rather than read as a field path. It runs against
+// the database at MONGODB_URI.
+func TestFindIssueIDsLiteralValue(t *testing.T) {
+	client := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	errorRepo := repo.NewErrorRepository(client)
+	projectID := bson.NewObjectID()
+	literalIssue := bson.NewObjectID()
+	otherIssue := bson.NewObjectID()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		coll := client.Database("portobello").Collection(repo.ERROR_COLLECTION)
+		_, _ = coll.DeleteMany(ctx, bson.D{{Key: "project_id", Value: projectID}})
+	})
+
+	events := []*repo.Error{
+		{ProjectID: projectID, IssueID: literalIssue, Context: map[string]string{"browser": "$context.browser"}},
+		{ProjectID: projectID, IssueID: otherIssue, Context: map[string]string{"browser": "Firefox"}},
+	}
+	for _, e := range events {
+		if _, err := errorRepo.CreateError(ctx, e); err != nil {
+			t.Fatalf("failed to create error: %v", err)
+		}
+	}
+
+	for _, value := range []string{"$context.browser", "$$ROOT"} {
+		ids, err := errorRepo.FindIssueIDs(ctx, projectID, nil, map[string]string{"browser": value})
+		if err != nil {
+			t.Fatalf("failed to find issues: %v", err)
+		}
+
+		want := 0
+		if value == "$context.browser" {
+			want = 1
+		}
+		if len(ids) != want {
+			t.Fatalf("%q: expected %d issues, got %d", value, want, len(ids))
+		}
+		if want == 1 && ids[0] != literalIssue {
+			t.Fatalf("%q: expected issue %s, got %s", value, literalIssue.Hex(), ids[0].Hex())
+		}
+	}
+}
+
+// newTestClient connects to the database at MONGODB_URI, skipping the test
+// when it is not set.
+func newTestClient(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+
+	client, err := db.NewDatabase()
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect(context.Background())
+	})
+
+	return client
+}
@@ -14,14 +14,78 @@ import (
 const ISSUE_COLLECTION = "issues"
 
 type Issue struct {
-	ID          bson.ObjectID    `bson:"_id,omitempty"`
-	ProjectID   bson.ObjectID    `bson:"project_id,omitempty"`
-	Title       string           `bson:"title,omitempty"`
-	Fingerprint string           `bson:"fingerprint,omitempty"`
-	Count       int              `bson:"count"`
-	FirstSeen   time.Time        `bson:"first_seen,omitempty"`
-	LastSeen    time.Time        `bson:"last_seen,omitempty"`
-	Status      model.IssueState `bson:"status"`
+	ID                 bson.ObjectID    `bson:"_id,omitempty"`
+	ProjectID          bson.ObjectID    `bson:"project_id,omitempty"`
+	Title              string           `bson:"title,omitempty"`
+	Fingerprint        string           `bson:"fingerprint,omitempty"`
+	FingerprintVersion int              `bson:"fingerprint_version,omitempty"`
+	Count              int              `bson:"count"`
+	FirstSeen          time.Time        `bson:"first_seen,omitempty"`
+	LastSeen           time.Time        `bson:"last_seen,omitempty"`
+	Status             model.IssueState `bson:"status"`
+	StatusChangedBy    bson.ObjectID    `bson:"status_changed_by,omitempty"`
+	StatusChangedAt    *time.Time       `bson:"status_changed_at,omitempty"`
+	Ignore             *IgnoreCondition `bson:"ignore,omitempty"`
+	Regressed          bool             `bson:"regressed,omitempty"`
+	RegressedAt        *time.Time       `bson:"regressed_at,omitempty"`
+	RegressionEventID  bson.ObjectID    `bson:"regression_event_id,omitempty"`
+	ResolvedInRelease  string           `bson:"resolved_in_release,omitempty"`
+	Environments       []string         `bson:"environments,omitempty"`
+	// EnvironmentStats holds the count and first and last seen times of
+	// the issue per environment.
+	EnvironmentStats map[string]EnvironmentStats `bson:"environment_stats,omitempty"`
+	Releases         []string                    `bson:"releases,omitempty"`
+	// MergedFingerprints are the fingerprints of the issues merged into
+	// this one. A merged issue is kept with MergedInto set so its
+	// fingerprint keeps resolving to the issue it was merged into.
+	MergedFingerprints []string      `bson:"merged_fingerprints,omitempty"`
+	MergedInto         bson.ObjectID `bson:"merged_into,omitempty"`
+	// LevelRank is the model.LevelRank of the most severe level seen.
+	LevelRank     int    `bson:"level_rank,omitempty"`
+	ExceptionType string `bson:"exception_type,omitempty"`
+	UserCount     int    `bson:"user_count,omitempty"`
+}
+
+type EnvironmentStats struct {
+	Count     int       `bson:"count"`
+	FirstSeen time.Time `bson:"first_seen"`
+	LastSeen  time.Time `bson:"last_seen"`
+}
+
+// IgnoreCondition describes when an ignored issue becomes unresolved again:
+// once Until has passed, once Count more events occurred or, with Window set,
+// once Count events occurred within Window minutes.
+type IgnoreCondition struct {
+	Until      *time.Time `bson:"until,omitempty"`
+	Count      int        `bson:"count,omitempty"`
+	Window     int        `bson:"window,omitempty"`
+	StartCount int        `bson:"start_count"`
+	IgnoredAt  time.Time  `bson:"ignored_at"`
+}
+
+// IssueFilter narrows an issue listing. Zero-valued fields don't filter.
+type IssueFilter struct {
+	Status        *model.IssueState
+	FirstSeenFrom *time.Time
+	FirstSeenTo   *time.Time
+	LastSeenFrom  *time.Time
+	LastSeenTo    *time.Time
+	MinCount      int
+	Environment   string
+	Release       string
+	LevelRank     int
+	// Text is a $text search on the title.
+	Text string
+	// IDs, when not nil, restricts the issues to these IDs.
+	IDs []bson.ObjectID
+}
+
+// IssueCursor is the position of the last issue of a page. Time holds the
+// sort value for the date sorts and Count the value for the count sort.
+type IssueCursor struct {
+	Time  time.Time
+	Count int
+	ID    bson.ObjectID
 }
 
 type IssueRepository struct {
@@ -32,10 +96,53 @@ func NewIssueRepository(db *mongo.Client) *IssueRepository {
 	return &IssueRepository{db: db}
 }
 
-func (r *IssueRepository) FindIssue(ctx context.Context, fingerprint string) (*Issue, error) {
+// EnsureIndexes creates the indexes issue grouping relies on. Issues are
+// unique per project and fingerprint.
+func (r *IssueRepository) EnsureIndexes(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "project_id", Value: 1}, {Key: "fingerprint", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "last_seen", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "first_seen", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "count", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "environments", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create issue indexes: %s", err)
+	}
+
+	users := r.db.Database("portobello").Collection(ISSUE_USER_COLLECTION)
+
+	_, err = users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "issue_id", Value: 1}, {Key: "user_key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create issue user indexes: %s", err)
+	}
+
+	return nil
+}
+
+func (r *IssueRepository) FindIssue(ctx context.Context, projectID bson.ObjectID, fingerprint string) (*Issue, error) {
 	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
 
-	result := coll.FindOne(ctx, bson.D{{Key: "fingerprint", Value: fingerprint}})
+	result := coll.FindOne(ctx, bson.D{{Key: "project_id", Value: projectID}, {Key: "fingerprint", Value: fingerprint}})
 	if result.Err() == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -53,14 +160,28 @@ func (r *IssueRepository) FindIssue(ctx context.Context, fingerprint string) (*I
 	return &i, nil
 }
 
+// UpsertIssue records one occurrence of issue in a single atomic update:
+// the count is incremented, last_seen advanced and the environments and
+// releases of issue added on an existing issue, while the remaining fields
+// of issue are only written when it is first created.
 func (r *IssueRepository) UpsertIssue(ctx context.Context, issue *Issue) (*Issue, error) {
 	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
 
 	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
-	filter := bson.D{{Key: "fingerprint", Value: issue.Fingerprint}}
-	update := bson.D{{Key: "$set", Value: issue}}
+	filter := bson.D{{Key: "project_id", Value: issue.ProjectID}, {Key: "fingerprint", Value: issue.Fingerprint}}
+	update := append(occurrenceUpdate(issue, false), bson.E{Key: "$setOnInsert", Value: bson.D{
+		{Key: "title", Value: issue.Title},
+		{Key: "fingerprint_version", Value: issue.FingerprintVersion},
+		{Key: "first_seen", Value: issue.FirstSeen},
+		{Key: "status", Value: issue.Status},
+	}})
 
 	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		// A concurrent upsert created the issue first, so this attempt
+		// now matches it and only increments.
+		result = coll.FindOneAndUpdate(ctx, filter, update, opts)
+	}
 	if result.Err() != nil {
 		return nil, fmt.Errorf("failed to upsert issue: %s", result.Err().Error())
 	}
@@ -75,15 +196,347 @@ func (r *IssueRepository) UpsertIssue(ctx context.Context, issue *Issue) (*Issue
 	return &i, nil
 }
 
-func (r *IssueRepository) FindIssuesByProject(ctx context.Context, projectId string) ([]Issue, error) {
+// RecordOccurrence records one occurrence of issue on the issue with id,
+// which an issue was merged into.
+func (r *IssueRepository) RecordOccurrence(ctx context.Context, id bson.ObjectID, issue *Issue) (*Issue, error) {
 	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
 
-	d, err := bson.ObjectIDFromHex(projectId)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := occurrenceUpdate(issue, true)
+
+	result := coll.FindOneAndUpdate(ctx, bson.D{{Key: "_id", Value: id}}, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to record occurrence: %s", result.Err().Error())
+	}
+
+	var i Issue
+
+	err := result.Decode(&i)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch issues: %s", err)
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// occurrenceUpdate increments the count, advances last_seen and the level,
+// sets the exception type and adds the environments and releases of issue,
+// updating the statistics of its environments alike. With firstSeen set,
+// first_seen is moved back to the one of issue if it is earlier.
+func occurrenceUpdate(issue *Issue, firstSeen bool) bson.D {
+	inc := bson.D{{Key: "count", Value: 1}}
+	maxFields := bson.D{
+		{Key: "last_seen", Value: issue.LastSeen},
+		{Key: "level_rank", Value: issue.LevelRank},
+	}
+	minFields := bson.D{}
+	if firstSeen {
+		minFields = append(minFields, bson.E{Key: "first_seen", Value: issue.FirstSeen})
+	}
+
+	for _, env := range issue.Environments {
+		prefix := "environment_stats." + env + "."
+		inc = append(inc, bson.E{Key: prefix + "count", Value: 1})
+		minFields = append(minFields, bson.E{Key: prefix + "first_seen", Value: issue.FirstSeen})
+		maxFields = append(maxFields, bson.E{Key: prefix + "last_seen", Value: issue.LastSeen})
+	}
+
+	update := bson.D{
+		{Key: "$inc", Value: inc},
+		{Key: "$max", Value: maxFields},
+	}
+	if len(minFields) > 0 {
+		update = append(update, bson.E{Key: "$min", Value: minFields})
+	}
+	if issue.ExceptionType != "" {
+		update = append(update, bson.E{Key: "$set", Value: bson.D{{Key: "exception_type", Value: issue.ExceptionType}}})
+	}
+
+	addToSet := bson.D{}
+	if len(issue.Environments) > 0 {
+		addToSet = append(addToSet, bson.E{Key: "environments", Value: bson.D{{Key: "$each", Value: issue.Environments}}})
+	}
+	if len(issue.Releases) > 0 {
+		addToSet = append(addToSet, bson.E{Key: "releases", Value: bson.D{{Key: "$each", Value: issue.Releases}}})
+	}
+	if len(addToSet) > 0 {
+		update = append(update, bson.E{Key: "$addToSet", Value: addToSet})
 	}
 
-	result, err := coll.Find(ctx, bson.D{{Key: "project_id", Value: d}})
+	return update
+}
+
+func (r *IssueRepository) FindIssueByID(ctx context.Context, projectID bson.ObjectID, id bson.ObjectID) (*Issue, error) {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	var i Issue
+
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "project_id", Value: projectID}}).Decode(&i)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find issue: %s", err)
+	}
+
+	return &i, nil
+}
+
+// SetIssueStatus moves an issue to status and returns the issue as it was
+// before the change. It returns nil when the issue doesn't exist or already
+// has that status. Ignoring an issue stores ignore, with its start count
+// taken from the issue's current count, and may be repeated to change the
+// conditions; any other status clears them. Likewise, resolving with
+// resolvedInRelease may be repeated to change the release.
+func (r *IssueRepository) SetIssueStatus(ctx context.Context, projectID bson.ObjectID, id bson.ObjectID, status model.IssueState, ignore *IgnoreCondition, resolvedInRelease string, userID bson.ObjectID, at time.Time) (*Issue, error) {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "project_id", Value: projectID},
+		{Key: "merged_into", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	if status != model.IssueStateIgnored && resolvedInRelease == "" {
+		filter = append(filter, bson.E{Key: "status", Value: bson.D{{Key: "$ne", Value: status}}})
+	}
+
+	set := bson.D{
+		{Key: "status", Value: status},
+		{Key: "status_changed_by", Value: userID},
+		{Key: "status_changed_at", Value: at},
+	}
+	if status == model.IssueStateResolved {
+		set = append(set, bson.E{Key: "regressed", Value: false})
+	}
+	if resolvedInRelease != "" {
+		// Strings in a pipeline starting with "$" are field paths, so the
+		// user-supplied release is set as a literal.
+		set = append(set, bson.E{Key: "resolved_in_release", Value: bson.D{{Key: "$literal", Value: resolvedInRelease}}})
+	}
+
+	// A pipeline update lets the ignore condition capture the current count.
+	update := mongo.Pipeline{{{Key: "$set", Value: set}}}
+	if status == model.IssueStateIgnored && ignore != nil {
+		condition := bson.D{{Key: "start_count", Value: "$count"}, {Key: "ignored_at", Value: at}}
+		if ignore.Until != nil {
+			condition = append(condition, bson.E{Key: "until", Value: *ignore.Until})
+		}
+		if ignore.Count > 0 {
+			condition = append(condition, bson.E{Key: "count", Value: ignore.Count})
+		}
+		if ignore.Window > 0 {
+			condition = append(condition, bson.E{Key: "window", Value: ignore.Window})
+		}
+		// $set merges into an existing subdocument, so the previous
+		// conditions are removed first rather than kept alongside new ones.
+		update = append(update,
+			bson.D{{Key: "$unset", Value: "ignore"}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "ignore", Value: condition}}}},
+		)
+	} else {
+		update = append(update, bson.D{{Key: "$unset", Value: "ignore"}})
+	}
+	if resolvedInRelease == "" {
+		update = append(update, bson.D{{Key: "$unset", Value: "resolved_in_release"}})
+	}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to update issue status: %s", result.Err().Error())
+	}
+
+	var i Issue
+
+	err := result.Decode(&i)
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// MarkRegression reopens a resolved issue because eventID occurred after it
+// was resolved. It returns nil if the issue is no longer resolved, so only
+// one of several concurrent events records the regression.
+func (r *IssueRepository) MarkRegression(ctx context.Context, id bson.ObjectID, eventID bson.ObjectID, at time.Time) (*Issue, error) {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "status", Value: model.IssueStateResolved}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: model.IssueStateUnresolved},
+		{Key: "regressed", Value: true},
+		{Key: "regressed_at", Value: at},
+		{Key: "regression_event_id", Value: eventID},
+		{Key: "status_changed_at", Value: at},
+	}}, {Key: "$unset", Value: bson.D{
+		{Key: "status_changed_by", Value: ""},
+		{Key: "resolved_in_release", Value: ""},
+	}}}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to mark regression: %s", result.Err().Error())
+	}
+
+	var i Issue
+
+	err := result.Decode(&i)
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// Unignore reopens an ignored issue whose ignore condition was met. It
+// returns nil if the issue is no longer ignored.
+func (r *IssueRepository) Unignore(ctx context.Context, id bson.ObjectID, at time.Time) (*Issue, error) {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "status", Value: model.IssueStateIgnored}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "status", Value: model.IssueStateUnresolved},
+			{Key: "status_changed_at", Value: at},
+		}},
+		{Key: "$unset", Value: bson.D{
+			{Key: "ignore", Value: ""},
+			{Key: "status_changed_by", Value: ""},
+		}},
+	}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to unignore issue: %s", result.Err().Error())
+	}
+
+	var i Issue
+
+	err := result.Decode(&i)
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// MergeIssues merges secondaries into the primary issue. The secondaries,
+// and the issues previously merged into them, first point at the primary so
+// new events for them are grouped into it; their fingerprints are then
+// added to the primary. The primary's statistics are left to be recomputed
+// once the events are moved, see SetIssueStats.
+func (r *IssueRepository) MergeIssues(ctx context.Context, primaryID bson.ObjectID, secondaries []Issue) error {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	ids := make([]bson.ObjectID, 0, len(secondaries))
+	fingerprints := []string{}
+
+	for _, s := range secondaries {
+		ids = append(ids, s.ID)
+		fingerprints = append(fingerprints, s.Fingerprint)
+		fingerprints = append(fingerprints, s.MergedFingerprints...)
+	}
+
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		bson.D{{Key: "merged_into", Value: bson.D{{Key: "$in", Value: ids}}}},
+	}}}
+	_, err := coll.UpdateMany(ctx, filter, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "merged_into", Value: primaryID}}},
+		{Key: "$unset", Value: bson.D{{Key: "merged_fingerprints", Value: ""}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge issues: %s", err)
+	}
+
+	_, err = coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: primaryID}}, bson.D{
+		{Key: "$addToSet", Value: bson.D{
+			{Key: "merged_fingerprints", Value: bson.D{{Key: "$each", Value: fingerprints}}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge issues: %s", err)
+	}
+
+	return nil
+}
+
+// SetIssueStats replaces the statistics of an issue with stats.
+func (r *IssueRepository) SetIssueStats(ctx context.Context, id bson.ObjectID, stats IssueStats) error {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	_, err := coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{{Key: "$set", Value: stats.fields()}})
+	if err != nil {
+		return fmt.Errorf("failed to update issue stats: %s", err)
+	}
+
+	return nil
+}
+
+// UnmergeIssue splits the merged issue back out of the issue it was merged
+// into and replaces the statistics of both with stats.
+func (r *IssueRepository) UnmergeIssue(ctx context.Context, primaryID bson.ObjectID, primaryStats IssueStats, mergedID bson.ObjectID, fingerprint string, mergedStats IssueStats) error {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	_, err := coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: mergedID}}, bson.D{
+		{Key: "$set", Value: mergedStats.fields()},
+		{Key: "$unset", Value: bson.D{{Key: "merged_into", Value: ""}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unmerge issue: %s", err)
+	}
+
+	_, err = coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: primaryID}}, bson.D{
+		{Key: "$set", Value: primaryStats.fields()},
+		{Key: "$pull", Value: bson.D{{Key: "merged_fingerprints", Value: fingerprint}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unmerge issue: %s", err)
+	}
+
+	return nil
+}
+
+// FindIssues returns up to limit issues of a project matching filter, in
+// descending order of the sort field and then ID. With after set, only the
+// issues following that cursor are returned.
+func (r *IssueRepository) FindIssues(ctx context.Context, projectID bson.ObjectID, filter IssueFilter, sort string, after *IssueCursor, limit int) ([]Issue, error) {
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	query := issueFilterQuery(projectID, filter)
+
+	if after != nil {
+		var value any = after.Time
+		if sort == model.IssueSortCount {
+			value = after.Count
+		}
+
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: sort, Value: bson.D{{Key: "$lt", Value: value}}}},
+			bson.D{{Key: sort, Value: value}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: after.ID}}}},
+		}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: sort, Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	result, err := coll.Find(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch issues: %s", err)
 	}
@@ -97,3 +550,55 @@ func (r *IssueRepository) FindIssuesByProject(ctx context.Context, projectId str
 
 	return i, nil
 }
+
+func issueFilterQuery(projectID bson.ObjectID, filter IssueFilter) bson.D {
+	query := bson.D{
+		{Key: "project_id", Value: projectID},
+		{Key: "merged_into", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	if filter.Status != nil {
+		query = append(query, bson.E{Key: "status", Value: *filter.Status})
+	}
+	if r := timeRange(filter.FirstSeenFrom, filter.FirstSeenTo); r != nil {
+		query = append(query, bson.E{Key: "first_seen", Value: r})
+	}
+	if r := timeRange(filter.LastSeenFrom, filter.LastSeenTo); r != nil {
+		query = append(query, bson.E{Key: "last_seen", Value: r})
+	}
+	if filter.MinCount > 0 {
+		query = append(query, bson.E{Key: "count", Value: bson.D{{Key: "$gte", Value: filter.MinCount}}})
+	}
+	if filter.Environment != "" {
+		query = append(query, bson.E{Key: "environments", Value: filter.Environment})
+	}
+	if filter.Release != "" {
+		query = append(query, bson.E{Key: "releases", Value: filter.Release})
+	}
+	if filter.LevelRank > 0 {
+		query = append(query, bson.E{Key: "level_rank", Value: filter.LevelRank})
+	}
+	if filter.Text != "" {
+		query = append(query, bson.E{Key: "$text", Value: bson.D{{Key: "$search", Value: filter.Text}}})
+	}
+	if filter.IDs != nil {
+		query = append(query, bson.E{Key: "_id", Value: bson.D{{Key: "$in", Value: filter.IDs}}})
+	}
+
+	return query
+}
+
+// timeRange builds an inclusive range condition, or nil if both bounds
+// are unset.
+func timeRange(from *time.Time, to *time.Time) bson.D {
+	var r bson.D
+
+	if from != nil {
+		r = append(r, bson.E{Key: "$gte", Value: *from})
+	}
+	if to != nil {
+		r = append(r, bson.E{Key: "$lte", Value: *to})
+	}
+
+	return r
+}
@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TestSetIssueStatusReplacesIgnore checks that ignoring an already ignored
+// issue replaces its conditions instead of merging them with the old ones.
+func TestSetIssueStatusReplacesIgnore(t *testing.T) {
+	client := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	issueRepo := repo.NewIssueRepository(client)
+	projectID := bson.NewObjectID()
+	userID := bson.NewObjectID()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		coll := client.Database("portobello").Collection(repo.ISSUE_COLLECTION)
+		_, _ = coll.DeleteMany(ctx, bson.D{{Key: "project_id", Value: projectID}})
+	})
+
+	now := time.Now().Truncate(time.Millisecond)
+	issue, err := issueRepo.UpsertIssue(ctx, &repo.Issue{
+		ProjectID:   projectID,
+		Title:       "ignore test",
+		Fingerprint: "ignore-test",
+		FirstSeen:   now,
+		LastSeen:    now,
+		Status:      model.IssueStateUnresolved,
+	})
+	if err != nil {
+		t.Fatalf("failed to create issue: %v", err)
+	}
+
+	until := now.Add(time.Hour)
+	first := &repo.IgnoreCondition{Until: &until, Count: 10, Window: 60}
+	if _, err := issueRepo.SetIssueStatus(ctx, projectID, issue.ID, model.IssueStateIgnored, first, "", userID, now); err != nil {
+		t.Fatalf("failed to ignore issue: %v", err)
+	}
+
+	second := &repo.IgnoreCondition{Count: 5}
+	if _, err := issueRepo.SetIssueStatus(ctx, projectID, issue.ID, model.IssueStateIgnored, second, "", userID, now); err != nil {
+		t.Fatalf("failed to ignore issue again: %v", err)
+	}
+
+	got, err := issueRepo.FindIssueByID(ctx, projectID, issue.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch issue: %v", err)
+	}
+	if got == nil || got.Ignore == nil {
+		t.Fatalf("expected an ignore condition, got %+v", got)
+	}
+	if got.Ignore.Count != 5 || got.Ignore.Until != nil || got.Ignore.Window != 0 {
+		t.Fatalf("expected only count 5, got %+v", got.Ignore)
+	}
+	if got.Ignore.StartCount != issue.Count {
+		t.Fatalf("expected start count %d, got %d", issue.Count, got.Ignore.StartCount)
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const ISSUE_USER_COLLECTION = "issue_users"
+
+// IssueUser records that the user identified by UserKey was affected by an
+// issue. Issues count their users from these records.
+type IssueUser struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	IssueID   bson.ObjectID `bson:"issue_id"`
+	UserKey   string        `bson:"user_key"`
+	FirstSeen time.Time     `bson:"first_seen"`
+}
+
+// AddIssueUser records that userKey was affected by an issue, incrementing
+// its user count the first time.
+func (r *IssueRepository) AddIssueUser(ctx context.Context, issueID bson.ObjectID, userKey string, at time.Time) error {
+	users := r.db.Database("portobello").Collection(ISSUE_USER_COLLECTION)
+
+	_, err := users.InsertOne(ctx, IssueUser{IssueID: issueID, UserKey: userKey, FirstSeen: at})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add issue user: %s", err)
+	}
+
+	coll := r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	_, err = coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: issueID}}, bson.D{{Key: "$inc", Value: bson.D{{Key: "user_count", Value: 1}}}})
+	if err != nil {
+		return fmt.Errorf("failed to count issue user: %s", err)
+	}
+
+	return nil
+}
+
+// MergeIssueUsers adds the users of the from issues to the to issue and
+// recounts its users.
+func (r *IssueRepository) MergeIssueUsers(ctx context.Context, from []bson.ObjectID, to bson.ObjectID) error {
+	users := r.db.Database("portobello").Collection(ISSUE_USER_COLLECTION)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "issue_id", Value: bson.D{{Key: "$in", Value: from}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_key"},
+			{Key: "first_seen", Value: bson.D{{Key: "$min", Value: "$first_seen"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "issue_id", Value: to},
+			{Key: "user_key", Value: "$_id"},
+			{Key: "first_seen", Value: 1},
+		}}},
+	}
+
+	return r.mergeUsersInto(ctx, users, pipeline, to)
+}
+
+// RebuildIssueUsers replaces the users of an issue with the ones of its
+// errors and recounts them.
+func (r *IssueRepository) RebuildIssueUsers(ctx context.Context, issueID bson.ObjectID) error {
+	users := r.db.Database("portobello").Collection(ISSUE_USER_COLLECTION)
+
+	_, err := users.DeleteMany(ctx, bson.D{{Key: "issue_id", Value: issueID}})
+	if err != nil {
+		return fmt.Errorf("failed to delete issue users: %s", err)
+	}
+
+	events := r.db.Database("portobello").Collection(ERROR_COLLECTION)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "issue_id", Value: issueID},
+			{Key: "user_key", Value: bson.D{{Key: "$exists", Value: true}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_key"},
+			{Key: "first_seen", Value: bson.D{{Key: "$min", Value: "$timestamp"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "issue_id", Value: issueID},
+			{Key: "user_key", Value: "$_id"},
+			{Key: "first_seen", Value: 1},
+		}}},
+	}
+
+	return r.mergeUsersInto(ctx, events, pipeline, issueID)
+}
+
+// mergeUsersInto runs pipeline on coll, stores the issue users it yields
+// and then sets the user count of issueID.
+func (r *IssueRepository) mergeUsersInto(ctx context.Context, coll *mongo.Collection, pipeline mongo.Pipeline, issueID bson.ObjectID) error {
+	pipeline = append(pipeline, bson.D{{Key: "$merge", Value: bson.D{
+		{Key: "into", Value: ISSUE_USER_COLLECTION},
+		{Key: "on", Value: bson.A{"issue_id", "user_key"}},
+		{Key: "whenMatched", Value: "keepExisting"},
+		{Key: "whenNotMatched", Value: "insert"},
+	}}})
+
+	result, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to merge issue users: %s", err)
+	}
+	if err := result.Close(ctx); err != nil {
+		return err
+	}
+
+	users := r.db.Database("portobello").Collection(ISSUE_USER_COLLECTION)
+
+	n, err := users.CountDocuments(ctx, bson.D{{Key: "issue_id", Value: issueID}})
+	if err != nil {
+		return fmt.Errorf("failed to count issue users: %s", err)
+	}
+
+	coll = r.db.Database("portobello").Collection(ISSUE_COLLECTION)
+
+	_, err = coll.UpdateOne(ctx, bson.D{{Key: "_id", Value: issueID}}, bson.D{{Key: "$set", Value: bson.D{{Key: "user_count", Value: n}}}})
+	if err != nil {
+		return fmt.Errorf("failed to count issue users: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	ORGANIZATION_COLLECTION = "organizations"
+	MEMBERSHIP_COLLECTION   = "memberships"
+	INVITE_COLLECTION       = "invites"
+)
+
+type Organization struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	Name      string        `bson:"name,omitempty"`
+	CreatedBy bson.ObjectID `bson:"created_by,omitempty"`
+	CreatedAt time.Time     `bson:"created_at,omitempty"`
+	UpdatedAt time.Time     `bson:"updated_at,omitempty"`
+}
+
+type Membership struct {
+	ID             bson.ObjectID `bson:"_id,omitempty"`
+	OrganizationID bson.ObjectID `bson:"organization_id"`
+	UserID         bson.ObjectID `bson:"user_id"`
+	Role           string        `bson:"role"`
+	CreatedAt      time.Time     `bson:"created_at,omitempty"`
+}
+
+type Invite struct {
+	ID             bson.ObjectID `bson:"_id,omitempty"`
+	OrganizationID bson.ObjectID `bson:"organization_id"`
+	Email          string        `bson:"email"`
+	Role           string        `bson:"role"`
+	Token          string        `bson:"token"`
+	InvitedBy      bson.ObjectID `bson:"invited_by"`
+	CreatedAt      time.Time     `bson:"created_at"`
+	ExpiresAt      time.Time     `bson:"expires_at"`
+	AcceptedAt     *time.Time    `bson:"accepted_at,omitempty"`
+}
+
+type OrganizationRepository struct {
+	db *mongo.Client
+}
+
+func NewOrganizationRepository(db *mongo.Client) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// EnsureIndexes creates the indexes backing membership lookups. A user is a
+// member of an organization at most once, and invite tokens are unique.
+func (r *OrganizationRepository) EnsureIndexes(ctx context.Context) error {
+	memberships := r.db.Database("portobello").Collection(MEMBERSHIP_COLLECTION)
+
+	_, err := memberships.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create membership indexes: %s", err)
+	}
+
+	invites := r.db.Database("portobello").Collection(INVITE_COLLECTION)
+
+	_, err = invites.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "token", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create invite indexes: %s", err)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepository) CreateOrganization(ctx context.Context, org *Organization) (*Organization, error) {
+	coll := r.db.Database("portobello").Collection(ORGANIZATION_COLLECTION)
+
+	result, err := coll.InsertOne(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("insert organization: %s", err)
+	}
+
+	org.ID = result.InsertedID.(bson.ObjectID)
+
+	return org, nil
+}
+
+func (r *OrganizationRepository) GetOrganizationByID(ctx context.Context, id bson.ObjectID) (*Organization, error) {
+	coll := r.db.Database("portobello").Collection(ORGANIZATION_COLLECTION)
+
+	var org Organization
+
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&org)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization: %s", err)
+	}
+
+	return &org, nil
+}
+
+func (r *OrganizationRepository) FindOrganizationsByIDs(ctx context.Context, ids []bson.ObjectID) ([]Organization, error) {
+	coll := r.db.Database("portobello").Collection(ORGANIZATION_COLLECTION)
+
+	result, err := coll.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organizations: %s", err)
+	}
+
+	var o []Organization
+
+	err = result.All(ctx, &o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode organizations: %s", err)
+	}
+
+	return o, nil
+}
+
+func (r *OrganizationRepository) GetMembership(ctx context.Context, orgID bson.ObjectID, userID bson.ObjectID) (*Membership, error) {
+	coll := r.db.Database("portobello").Collection(MEMBERSHIP_COLLECTION)
+
+	var m Membership
+
+	err := coll.FindOne(ctx, bson.D{{Key: "organization_id", Value: orgID}, {Key: "user_id", Value: userID}}).Decode(&m)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find membership: %s", err)
+	}
+
+	return &m, nil
+}
+
+func (r *OrganizationRepository) FindMembershipsByUser(ctx context.Context, userID bson.ObjectID) ([]Membership, error) {
+	return r.findMemberships(ctx, bson.D{{Key: "user_id", Value: userID}})
+}
+
+func (r *OrganizationRepository) FindMembershipsByOrganization(ctx context.Context, orgID bson.ObjectID) ([]Membership, error) {
+	return r.findMemberships(ctx, bson.D{{Key: "organization_id", Value: orgID}})
+}
+
+func (r *OrganizationRepository) findMemberships(ctx context.Context, filter bson.D) ([]Membership, error) {
+	coll := r.db.Database("portobello").Collection(MEMBERSHIP_COLLECTION)
+
+	result, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memberships: %s", err)
+	}
+
+	var m []Membership
+
+	err = result.All(ctx, &m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode memberships: %s", err)
+	}
+
+	return m, nil
+}
+
+// AddMembership makes the user a member of the organization. An existing
+// membership is left untouched.
+func (r *OrganizationRepository) AddMembership(ctx context.Context, m *Membership) (*Membership, error) {
+	coll := r.db.Database("portobello").Collection(MEMBERSHIP_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	filter := bson.D{{Key: "organization_id", Value: m.OrganizationID}, {Key: "user_id", Value: m.UserID}}
+	update := bson.D{{Key: "$setOnInsert", Value: bson.D{
+		{Key: "role", Value: m.Role},
+		{Key: "created_at", Value: m.CreatedAt},
+	}}}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() != nil {
+		return nil, fmt.Errorf("insert membership: %s", result.Err().Error())
+	}
+
+	var membership Membership
+
+	err := result.Decode(&membership)
+	if err != nil {
+		return nil, err
+	}
+
+	return &membership, nil
+}
+
+func (r *OrganizationRepository) UpdateMembershipRole(ctx context.Context, orgID bson.ObjectID, userID bson.ObjectID, role string) (*Membership, error) {
+	coll := r.db.Database("portobello").Collection(MEMBERSHIP_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.D{{Key: "organization_id", Value: orgID}, {Key: "user_id", Value: userID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "role", Value: role}}}}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to update membership: %s", result.Err().Error())
+	}
+
+	var m Membership
+
+	err := result.Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (r *OrganizationRepository) DeleteMembership(ctx context.Context, orgID bson.ObjectID, userID bson.ObjectID) error {
+	coll := r.db.Database("portobello").Collection(MEMBERSHIP_COLLECTION)
+
+	_, err := coll.DeleteOne(ctx, bson.D{{Key: "organization_id", Value: orgID}, {Key: "user_id", Value: userID}})
+	if err != nil {
+		return fmt.Errorf("failed to delete membership: %s", err)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepository) CreateInvite(ctx context.Context, invite *Invite) (*Invite, error) {
+	coll := r.db.Database("portobello").Collection(INVITE_COLLECTION)
+
+	result, err := coll.InsertOne(ctx, invite)
+	if err != nil {
+		return nil, fmt.Errorf("insert invite: %s", err)
+	}
+
+	invite.ID = result.InsertedID.(bson.ObjectID)
+
+	return invite, nil
+}
+
+// FindPendingInvite returns the unexpired, unaccepted invite with token
+// addressed to email, or nil if there is none.
+func (r *OrganizationRepository) FindPendingInvite(ctx context.Context, token string, email string, now time.Time) (*Invite, error) {
+	coll := r.db.Database("portobello").Collection(INVITE_COLLECTION)
+
+	var invite Invite
+
+	err := coll.FindOne(ctx, pendingInviteFilter(token, email, now)).Decode(&invite)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invite: %s", err)
+	}
+
+	return &invite, nil
+}
+
+// AcceptInvite atomically marks a pending invite as accepted so a token can
+// only be redeemed once. It returns nil if the invite is no longer pending.
+func (r *OrganizationRepository) AcceptInvite(ctx context.Context, token string, email string, now time.Time) (*Invite, error) {
+	coll := r.db.Database("portobello").Collection(INVITE_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "accepted_at", Value: now}}}}
+
+	result := coll.FindOneAndUpdate(ctx, pendingInviteFilter(token, email, now), update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to accept invite: %s", result.Err().Error())
+	}
+
+	var invite Invite
+
+	err := result.Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+func pendingInviteFilter(token string, email string, now time.Time) bson.D {
+	return bson.D{
+		{Key: "token", Value: token},
+		{Key: "email", Value: email},
+		{Key: "accepted_at", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+	}
+}
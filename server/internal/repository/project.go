@@ -10,13 +10,47 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-const PROJECT_COLLECTION = "projects"
+const (
+	PROJECT_COLLECTION = "projects"
+	COUNTER_COLLECTION = "counters"
+)
+
+// sentryIDCounter is the counter document numbering projects for Sentry.
+const sentryIDCounter = "project_sentry_id"
+
+type GroupingRule struct {
+	Matcher     string   `bson:"matcher"`
+	Key         string   `bson:"key,omitempty"`
+	Pattern     string   `bson:"pattern,omitempty"`
+	Fingerprint []string `bson:"fingerprint"`
+}
+
+type ProjectKey struct {
+	ID         bson.ObjectID `bson:"_id"`
+	Label      string        `bson:"label,omitempty"`
+	PublicKey  string        `bson:"public_key"`
+	SecretKey  string        `bson:"secret_key,omitempty"`
+	RateLimit  int           `bson:"rate_limit,omitempty"`
+	CreatedAt  time.Time     `bson:"created_at"`
+	LastUsedAt *time.Time    `bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time    `bson:"revoked_at,omitempty"`
+}
 
 type Project struct {
-	ID        bson.ObjectID `bson:"_id,omitempty"`
-	Title     string        `bson:"title,omitempty"`
-	CreatedAt time.Time     `bson:"created_at,omitempty"`
-	UpdatedAt time.Time     `bson:"updated_at,omitempty"`
+	ID             bson.ObjectID `bson:"_id,omitempty"`
+	Title          string        `bson:"title,omitempty"`
+	OrganizationID bson.ObjectID `bson:"organization_id,omitempty"`
+	OwnerID        bson.ObjectID `bson:"owner_id,omitempty"`
+	// SentryID is the numeric project ID of the project's DSNs; Sentry
+	// SDKs reject DSNs whose project ID isn't a number.
+	SentryID      int64          `bson:"sentry_id,omitempty"`
+	Keys          []ProjectKey   `bson:"keys,omitempty"`
+	GroupingRules []GroupingRule `bson:"grouping_rules,omitempty"`
+	// GroupingRulesVersion is incremented whenever GroupingRules change, so
+	// compiled rules can be cached until then.
+	GroupingRulesVersion int       `bson:"grouping_rules_version,omitempty"`
+	CreatedAt            time.Time `bson:"created_at,omitempty"`
+	UpdatedAt            time.Time `bson:"updated_at,omitempty"`
 }
 
 type ProjectRepository struct {
@@ -27,28 +61,103 @@ func NewProjectRepository(db *mongo.Client) *ProjectRepository {
 	return &ProjectRepository{db: db}
 }
 
-// func (r *ProjectRepository) GetUserByID(ctx context.Context, id bson.ObjectID) (*User, error) {
-// 	coll := r.db.Database("portobello").Collection("users")
+// EnsureIndexes creates the indexes used to resolve a project from one of
+// its ingest keys. Public keys are unique across all projects.
+func (r *ProjectRepository) EnsureIndexes(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
 
-// 	var user User
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "keys.public_key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{
+			{Key: "keys.public_key", Value: bson.D{{Key: "$exists", Value: true}}},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create project indexes: %s", err)
+	}
 
-// 	err := coll.FindOne(context.TODO(), bson.D{{Key: "_id", Value: id}}).Decode(&user)
-// 	if err == mongo.ErrNoDocuments {
-// 		return nil, nil
-// 	}
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sentry_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{
+			{Key: "sentry_id", Value: bson.D{{Key: "$exists", Value: true}}},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create project indexes: %s", err)
+	}
 
-// 	return &user, nil
-// }
+	return nil
+}
+
+func (r *ProjectRepository) GetProjectByID(ctx context.Context, id bson.ObjectID) (*Project, error) {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	var project Project
+
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %s", err)
+	}
+
+	return &project, nil
+}
+
+func (r *ProjectRepository) GetProjectByKey(ctx context.Context, publicKey string) (*Project, error) {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	var project Project
+
+	err := coll.FindOne(ctx, bson.D{{Key: "keys.public_key", Value: publicKey}}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project: %s", err)
+	}
+
+	return &project, nil
+}
+
+func (r *ProjectRepository) FindProjectsByOrganization(ctx context.Context, orgID bson.ObjectID) ([]Project, error) {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	result, err := coll.Find(ctx, bson.D{{Key: "organization_id", Value: orgID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch projects: %s", err)
+	}
+
+	var p []Project
+
+	err = result.All(ctx, &p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode projects: %s", err)
+	}
+
+	return p, nil
+}
 
 func (r *ProjectRepository) CreateProject(ctx context.Context, project *Project) (*Project, error) {
 	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
 
+	sentryID, err := r.nextSentryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
-	filter := bson.D{{Key: "title", Value: project.Title}}
-	update := bson.D{{Key: "$set", Value: project}}
+	filter := bson.D{{Key: "title", Value: project.Title}, {Key: "organization_id", Value: project.OrganizationID}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "updated_at", Value: project.UpdatedAt}}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "owner_id", Value: project.OwnerID},
+			{Key: "sentry_id", Value: sentryID},
+			{Key: "keys", Value: project.Keys},
+			{Key: "created_at", Value: project.CreatedAt},
+		}},
+	}
 
 	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
 	if result.Err() != nil {
@@ -57,6 +166,123 @@ func (r *ProjectRepository) CreateProject(ctx context.Context, project *Project)
 
 	var p Project
 
+	err = result.Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// BackfillSentryIDs numbers the projects created before projects had a
+// SentryID.
+func (r *ProjectRepository) BackfillSentryIDs(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	missing := bson.D{{Key: "sentry_id", Value: bson.D{{Key: "$exists", Value: false}}}}
+
+	result, err := coll.Find(ctx, missing, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to fetch projects: %s", err)
+	}
+
+	var projects []Project
+
+	err = result.All(ctx, &projects)
+	if err != nil {
+		return fmt.Errorf("failed to decode projects: %s", err)
+	}
+
+	for _, p := range projects {
+		sentryID, err := r.nextSentryID(ctx)
+		if err != nil {
+			return err
+		}
+
+		filter := append(bson.D{{Key: "_id", Value: p.ID}}, missing...)
+		_, err = coll.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: bson.D{{Key: "sentry_id", Value: sentryID}}}})
+		if err != nil {
+			return fmt.Errorf("failed to set sentry id: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// nextSentryID allocates the next project SentryID.
+func (r *ProjectRepository) nextSentryID(ctx context.Context) (int64, error) {
+	coll := r.db.Database("portobello").Collection(COUNTER_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	result := coll.FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: sentryIDCounter}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: int64(1)}}}},
+		opts,
+	)
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := result.Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate sentry id: %s", err)
+	}
+
+	return counter.Seq, nil
+}
+
+func (r *ProjectRepository) UpdateGroupingRules(ctx context.Context, id bson.ObjectID, rules []GroupingRule) (*Project, error) {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "grouping_rules", Value: rules},
+			{Key: "updated_at", Value: time.Now()},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "grouping_rules_version", Value: 1}}},
+	}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to update grouping rules: %s", result.Err().Error())
+	}
+
+	var p Project
+
+	err := result.Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *ProjectRepository) AddProjectKey(ctx context.Context, id bson.ObjectID, key ProjectKey) (*Project, error) {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "keys", Value: key}}},
+		{Key: "$set", Value: bson.D{{Key: "updated_at", Value: time.Now()}}},
+	}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to add project key: %s", result.Err().Error())
+	}
+
+	var p Project
+
 	err := result.Decode(&p)
 	if err != nil {
 		return nil, err
@@ -65,6 +291,56 @@ func (r *ProjectRepository) CreateProject(ctx context.Context, project *Project)
 	return &p, nil
 }
 
+// RevokeProjectKey marks an active key of a project as revoked. It returns
+// nil when the project has no such active key.
+func (r *ProjectRepository) RevokeProjectKey(ctx context.Context, id bson.ObjectID, keyID bson.ObjectID) (*Project, error) {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "keys", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "_id", Value: keyID},
+			{Key: "revoked_at", Value: bson.D{{Key: "$exists", Value: false}}},
+		}}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "keys.$.revoked_at", Value: time.Now()},
+		{Key: "updated_at", Value: time.Now()},
+	}}}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to revoke project key: %s", result.Err().Error())
+	}
+
+	var p Project
+
+	err := result.Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *ProjectRepository) TouchProjectKey(ctx context.Context, publicKey string, usedAt time.Time) error {
+	coll := r.db.Database("portobello").Collection(PROJECT_COLLECTION)
+
+	filter := bson.D{{Key: "keys.public_key", Value: publicKey}}
+	update := bson.D{{Key: "$max", Value: bson.D{{Key: "keys.$.last_used_at", Value: usedAt}}}}
+
+	_, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update project key: %s", err)
+	}
+
+	return nil
+}
+
 // func (r *ProjectRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
 // 	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
 // 	if err != nil {
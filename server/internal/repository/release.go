@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const RELEASE_COLLECTION = "releases"
+
+type Release struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	ProjectID bson.ObjectID `bson:"project_id"`
+	Version   string        `bson:"version"`
+	FirstSeen time.Time     `bson:"first_seen"`
+	LastSeen  time.Time     `bson:"last_seen,omitempty"`
+}
+
+type ReleaseRepository struct {
+	db *mongo.Client
+}
+
+func NewReleaseRepository(db *mongo.Client) *ReleaseRepository {
+	return &ReleaseRepository{db: db}
+}
+
+func (r *ReleaseRepository) EnsureIndexes(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection(RELEASE_COLLECTION)
+
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "project_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create release indexes: %s", err)
+	}
+
+	return nil
+}
+
+// UpsertRelease records that version of a project was seen at seenAt,
+// creating the release the first time.
+func (r *ReleaseRepository) UpsertRelease(ctx context.Context, projectID bson.ObjectID, version string, seenAt time.Time) (*Release, error) {
+	coll := r.db.Database("portobello").Collection(RELEASE_COLLECTION)
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	filter := bson.D{{Key: "project_id", Value: projectID}, {Key: "version", Value: version}}
+	update := bson.D{
+		{Key: "$max", Value: bson.D{{Key: "last_seen", Value: seenAt}}},
+		{Key: "$setOnInsert", Value: bson.D{{Key: "first_seen", Value: seenAt}}},
+	}
+
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		result = coll.FindOneAndUpdate(ctx, filter, update, opts)
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("failed to upsert release: %s", result.Err().Error())
+	}
+
+	var rel Release
+
+	err := result.Decode(&rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rel, nil
+}
+
+func (r *ReleaseRepository) GetRelease(ctx context.Context, projectID bson.ObjectID, version string) (*Release, error) {
+	coll := r.db.Database("portobello").Collection(RELEASE_COLLECTION)
+
+	var rel Release
+
+	err := coll.FindOne(ctx, bson.D{{Key: "project_id", Value: projectID}, {Key: "version", Value: version}}).Decode(&rel)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find release: %s", err)
+	}
+
+	return &rel, nil
+}
+
+func (r *ReleaseRepository) FindReleasesByProject(ctx context.Context, projectID bson.ObjectID) ([]Release, error) {
+	coll := r.db.Database("portobello").Collection(RELEASE_COLLECTION)
+
+	opts := options.Find().SetSort(bson.D{{Key: "first_seen", Value: -1}})
+
+	result, err := coll.Find(ctx, bson.D{{Key: "project_id", Value: projectID}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %s", err)
+	}
+
+	var rel []Release
+
+	err = result.All(ctx, &rel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode releases: %s", err)
+	}
+
+	return rel, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,9 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// ErrDuplicateUser is returned when a user with the same email exists.
+var ErrDuplicateUser = errors.New("duplicate user")
+
 type User struct {
 	ID           bson.ObjectID `bson:"_id,omitempty"`
 	Username     string        `bson:"username,omitempty"`
@@ -27,12 +31,28 @@ func NewUserRepository(db *mongo.Client) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// EnsureIndexes creates the unique index on email. Emails are stored
+// normalized, so the index also rejects addresses differing only in case.
+func (r *UserRepository) EnsureIndexes(ctx context.Context) error {
+	coll := r.db.Database("portobello").Collection("users")
+
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user indexes: %s", err)
+	}
+
+	return nil
+}
+
 func (r *UserRepository) GetUserByID(ctx context.Context, id bson.ObjectID) (*User, error) {
 	coll := r.db.Database("portobello").Collection("users")
 
 	var user User
 
-	err := coll.FindOne(context.TODO(), bson.D{{Key: "_id", Value: id}}).Decode(&user)
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&user)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -43,52 +63,56 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id bson.ObjectID) (*Us
 	return &user, nil
 }
 
-// func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
-// 	query := `
-// 		SELECT id, username, email, password_hash, created_at, updated_at
-// 		FROM users
-// 		WHERE email = $1
-// 	`
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	coll := r.db.Database("portobello").Collection("users")
 
-// 	var user User
-// 	err := r.db.QueryRowContext(ctx, query, email).Scan(
-// 		&user.ID,
-// 		&user.Username,
-// 		&user.Email,
-// 		&user.PasswordHash,
-// 		&user.CreatedAt,
-// 		&user.UpdatedAt,
-// 	)
+	var user User
 
-// 	if err != nil {
-// 		if errors.Is(err, sql.ErrNoRows) {
-// 			return nil, nil // User not found
-// 		}
-// 		return nil, fmt.Errorf("query user by email: %w", err)
-// 	}
+	err := coll.FindOne(ctx, bson.D{{Key: "email", Value: email}}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query user by email: %w", err)
+	}
 
-// 	return &user, nil
-// }
+	return &user, nil
+}
 
-func (r *UserRepository) CreateUser(ctx context.Context, user *User) (*User, error) {
+func (r *UserRepository) FindUsersByIDs(ctx context.Context, ids []bson.ObjectID) ([]User, error) {
 	coll := r.db.Database("portobello").Collection("users")
 
-	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
-	filter := bson.D{{Key: "email", Value: user.Email}, {Key: "username", Value: user.Username}}
-	update := bson.D{{Key: "$set", Value: user}}
+	result, err := coll.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return nil, fmt.Errorf("query users: %w", err)
+	}
+
+	var u []User
 
-	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
-	if result.Err() != nil {
-		return nil, fmt.Errorf("insert user: %w", result.Err().Error())
+	err = result.All(ctx, &u)
+	if err != nil {
+		return nil, fmt.Errorf("decode users: %w", err)
 	}
 
-	var u User
+	return u, nil
+}
 
-	err := result.Decode(&u)
+func (r *UserRepository) CreateUser(ctx context.Context, user *User) (*User, error) {
+	coll := r.db.Database("portobello").Collection("users")
+
+	// Users are only ever inserted: signing up again with an existing email
+	// must not replace that account's password.
+	result, err := coll.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateUser
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("insert user: %w", err)
 	}
 
+	u := *user
+	u.ID = result.InsertedID.(bson.ObjectID)
+
 	return &u, nil
 }
 
@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TestCreateUserDuplicateEmail checks that signing up again with a
+// registered email is rejected and leaves the existing account unchanged.
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	client := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userRepo := repo.NewUserRepository(client)
+	if err := userRepo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("failed to create indexes: %v", err)
+	}
+
+	email := bson.NewObjectID().Hex() + "@example.com"
+	first, second := "first", "second"
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		coll := client.Database("portobello").Collection("users")
+		_, _ = coll.DeleteMany(ctx, bson.D{{Key: "email", Value: email}})
+	})
+
+	user, err := userRepo.CreateUser(ctx, &repo.User{Username: "owner", Email: email, PasswordHash: &first})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	_, err = userRepo.CreateUser(ctx, &repo.User{Username: "owner", Email: email, PasswordHash: &second})
+	if !errors.Is(err, repo.ErrDuplicateUser) {
+		t.Fatalf("expected ErrDuplicateUser, got %v", err)
+	}
+
+	got, err := userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("failed to fetch user: %v", err)
+	}
+	if got == nil || got.ID != user.ID || got.PasswordHash == nil || *got.PasswordHash != first {
+		t.Fatalf("expected the original account to be unchanged, got %+v", got)
+	}
+}
@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	model "github.com/dorianneto/bugfy/internal/api/model"
@@ -12,21 +18,79 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+var (
+	ErrEventNotFound = errors.New("event not found")
+	// ErrInvalidEvent wraps the reasons an ingested event is rejected.
+	ErrInvalidEvent = errors.New("invalid event")
+)
+
+var tagKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_.:-]+$`)
+
+const (
+	maxStackFrames       = 256
+	maxEnvironmentLength = 64
+	maxTags              = 50
+	maxTagKeyLength      = 32
+	maxTagValueLength    = 200
+	maxBreadcrumbs       = 100
+	maxCategoryLength    = 64
+	maxMessageLength     = 1024
+	maxBreadcrumbData    = 2048
+	maxRequestBody       = 16 << 10
+	maxExtra             = 16 << 10
+	defaultEventLimit    = 25
+	maxEventLimit        = 100
+)
+
 type ErrorService struct {
-	errorRepo    *repo.ErrorRepository
-	issueService *IssueService
-	timeout      time.Duration
+	errorRepo      *repo.ErrorRepository
+	projectRepo    *repo.ProjectRepository
+	issueService   *IssueService
+	releaseService *ReleaseService
+	projectService *ProjectService
+	groupingRules  *groupingRuleCache
+	timeout        time.Duration
 }
 
-func NewErrorService(errorRepo *repo.ErrorRepository, issueService *IssueService) *ErrorService {
+func NewErrorService(errorRepo *repo.ErrorRepository, projectRepo *repo.ProjectRepository, issueService *IssueService, releaseService *ReleaseService, projectService *ProjectService) *ErrorService {
 	return &ErrorService{
-		errorRepo:    errorRepo,
-		issueService: issueService,
-		timeout:      time.Duration(2) * time.Second,
+		errorRepo:      errorRepo,
+		projectRepo:    projectRepo,
+		issueService:   issueService,
+		releaseService: releaseService,
+		projectService: projectService,
+		groupingRules:  newGroupingRuleCache(),
+		timeout:        time.Duration(2) * time.Second,
 	}
 }
 
 func (s *ErrorService) CreateError(ctx context.Context, req model.RequestCreateError) (*model.ResponseCreateError, error) {
+	e, err := s.createError(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ResponseCreateError{
+		ID:                 e.ID.String(),
+		ProjectID:          e.ProjectID.String(),
+		Fingerprint:        e.Fingerprint,
+		FingerprintVersion: e.FingerprintVersion,
+		Message:            e.Message,
+		Type:               e.Type,
+		StackTrace:         toModelStackTrace(e.StackTrace),
+		Context:            e.Context,
+		Release:            e.Release,
+		Environment:        e.Environment,
+		Tags:               toModelTags(e.Tags),
+		Level:              e.Level,
+		Exception:          toModelException(e.Exception),
+		Timestamp:          e.Timestamp,
+	}, nil
+}
+
+// createError validates, stores and groups an event, returning the stored
+// error.
+func (s *ErrorService) createError(ctx context.Context, req model.RequestCreateError) (*repo.Error, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -35,16 +99,127 @@ func (s *ErrorService) CreateError(ctx context.Context, req model.RequestCreateE
 	pID, err := bson.ObjectIDFromHex(req.ProjectID)
 	if err != nil {
 		log.Printf("ErrorService.CreateError - convertion error: %v", err)
+		return nil, ErrProjectNotFound
+	}
+
+	project, err := s.projectRepo.GetProjectByID(ctx, pID)
+	if err != nil {
+		log.Printf("ErrorService.CreateError - Database error: %v", err)
 		return nil, fmt.Errorf("failed to create error: %v", err)
 	}
+	if project == nil {
+		log.Printf("ErrorService.CreateError - Project not found: %s", req.ProjectID)
+		return nil, ErrProjectNotFound
+	}
+
+	if len(req.StackTrace) > maxStackFrames {
+		log.Printf("ErrorService.CreateError - Validation failed: %d stack frames", len(req.StackTrace))
+		return nil, fmt.Errorf("%w: stack trace must have at most %d frames", ErrInvalidEvent, maxStackFrames)
+	}
+
+	for i, f := range req.StackTrace {
+		if f.Function == "" && f.Filename == "" && f.Module == "" {
+			log.Printf("ErrorService.CreateError - Validation failed: empty stack frame at %d", i)
+			return nil, fmt.Errorf("%w: stack frame %d must have a function, module or filename", ErrInvalidEvent, i)
+		}
+	}
+
+	release, err := normalizeRelease(req.Release)
+	if err != nil {
+		log.Printf("ErrorService.CreateError - Validation failed: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	environment, err := normalizeEnvironment(req.Environment)
+	if err != nil {
+		log.Printf("ErrorService.CreateError - Validation failed: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if environment == "" {
+		// Clients sent the environment as context before it had a field,
+		// and context values were never validated: one that can't be an
+		// environment stays in the context only.
+		environment, err = normalizeEnvironment(req.Context["environment"])
+		if err != nil {
+			log.Printf("ErrorService.CreateError - Ignoring context environment: %v", err)
+			environment = ""
+		}
+	}
+
+	tags, err := toRepoTags(req.Tags)
+	if err != nil {
+		log.Printf("ErrorService.CreateError - Validation failed: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	level := req.Level
+	if level == "" {
+		level = model.LevelError
+	}
+	if model.LevelRank(level) == 0 {
+		log.Printf("ErrorService.CreateError - Validation failed: unknown level %q", level)
+		return nil, fmt.Errorf("%w: unknown level %q", ErrInvalidEvent, level)
+	}
+
+	errorType := "error"
+	message := req.Message
+	var exception *repo.Exception
+
+	if req.Exception != nil && (req.Exception.Type != "" || req.Exception.Value != "") {
+		exception = &repo.Exception{Type: req.Exception.Type, Value: req.Exception.Value}
+		if exception.Type != "" {
+			errorType = exception.Type
+		}
+		if message == "" {
+			message = exception.Value
+		}
+	}
+
+	fingerprintType := ""
+	if exception != nil {
+		fingerprintType = exception.Type
+	}
+
+	now := time.Now()
+
+	breadcrumbs := toRepoBreadcrumbs(req.Breadcrumbs, now)
 
 	p := &repo.Error{
-		ProjectID:   pID,
-		Message:     req.Message,
-		Type:        "error",
-		Fingerprint: util.GenerateFingerprint(req.Message),
-		Context:     req.Context,
-		Timestamp:   time.Now(),
+		ProjectID: pID,
+		Message:   message,
+		Type:      errorType,
+		Fingerprint: util.GenerateFingerprint(util.FingerprintInput{
+			Type:    fingerprintType,
+			Message: message,
+			Frames:  toFingerprintFrames(req.StackTrace),
+		}),
+		FingerprintVersion: util.FingerprintVersion,
+		StackTrace:         toRepoStackTrace(req.StackTrace),
+		Context:            req.Context,
+		Release:            release,
+		Environment:        environment,
+		Tags:               tags,
+		Level:              level,
+		Exception:          exception,
+		User:               toRepoUser(req.User),
+		Request:            toRepoRequest(req.Request),
+		Runtime:            toRepoRuntime(req.Runtime),
+		OS:                 toRepoOS(req.OS),
+		Device:             toRepoDevice(req.Device),
+		Extra:              boundData(req.Extra, maxExtra),
+		Breadcrumbs:        breadcrumbs,
+		Timestamp:          now,
+	}
+	p.UserKey = userKey(p.User)
+
+	p.Fingerprint = groupingKey(s.groupingRules.rules(project), p, req.Fingerprint, p.Fingerprint)
+
+	// The release is recorded before grouping so regression checks can
+	// order it against the release an issue was resolved in.
+	if p.Release != "" {
+		if err := s.releaseService.RecordRelease(ctx, pID, p.Release, p.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to create error: %v", err)
+		}
 	}
 
 	e, err := s.errorRepo.CreateError(ctx, p)
@@ -61,13 +236,297 @@ func (s *ErrorService) CreateError(ctx context.Context, req model.RequestCreateE
 		return nil, fmt.Errorf("failed to group error: %v", err)
 	}
 
-	return &model.ResponseCreateError{
-		ID:          e.ID.String(),
-		ProjectID:   e.ProjectID.String(),
-		Fingerprint: e.Fingerprint,
-		Message:     e.Message,
-		Type:        e.Type,
-		Context:     e.Context,
-		Timestamp:   e.Timestamp,
-	}, nil
+	return e, nil
+}
+
+// GetIssueEvents returns one page of an issue's events, newest first,
+// along with the cursor of the next page, which is empty on the last page.
+func (s *ErrorService) GetIssueEvents(ctx context.Context, projectId string, issueId string, req model.RequestGetEvents) ([]model.ResponseEvent, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultEventLimit
+	}
+	if limit < 0 || limit > maxEventLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidIssueQuery, maxEventLimit)
+	}
+
+	var after *repo.ErrorCursor
+	if req.Cursor != "" {
+		c, err := decodeEventCursor(req.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = c
+	}
+
+	issue, err := s.issueService.findIssue(ctx, projectId, issueId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// One extra event is fetched to tell whether there is a next page.
+	e, err := s.errorRepo.FindErrorsByIssue(ctx, issue.ID, after, limit+1)
+	if err != nil {
+		log.Printf("ErrorService.GetIssueEvents - Database error: %v", err)
+		return nil, "", fmt.Errorf("failed to fetch events: %v", err)
+	}
+
+	next := ""
+	if len(e) > limit {
+		e = e[:limit]
+		next = encodeEventCursor(e[limit-1])
+	}
+
+	events := make([]model.ResponseEvent, 0, len(e))
+
+	for _, event := range e {
+		events = append(events, *toModelEvent(&event))
+	}
+
+	return events, next, nil
+}
+
+// GetIssueEdgeEvent returns the newest or, with newest unset, the oldest
+// event of an issue.
+func (s *ErrorService) GetIssueEdgeEvent(ctx context.Context, projectId string, issueId string, newest bool) (*model.ResponseEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	issue, err := s.issueService.findIssue(ctx, projectId, issueId)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := s.errorRepo.FindEdgeError(ctx, issue.ID, newest)
+	if err != nil {
+		log.Printf("ErrorService.GetIssueEdgeEvent - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch event: %v", err)
+	}
+	if e == nil {
+		return nil, ErrEventNotFound
+	}
+
+	return toModelEvent(e), nil
+}
+
+// GetEvent returns an event of a project the user may access. Events of
+// other projects are reported as ErrEventNotFound.
+func (s *ErrorService) GetEvent(ctx context.Context, eventId string, userID string) (*model.ResponseEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	id, err := bson.ObjectIDFromHex(eventId)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+
+	e, err := s.errorRepo.GetErrorByID(ctx, id)
+	if err != nil {
+		log.Printf("ErrorService.GetEvent - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch event: %v", err)
+	}
+	if e == nil {
+		return nil, ErrEventNotFound
+	}
+
+	_, err = s.projectService.AuthorizeProject(ctx, e.ProjectID.Hex(), userID, model.RoleMember)
+	if errors.Is(err, ErrProjectNotFound) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toModelEvent(e), nil
+}
+
+func toRepoStackTrace(frames []model.StackFrame) []repo.StackFrame {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	st := make([]repo.StackFrame, 0, len(frames))
+
+	for _, f := range frames {
+		st = append(st, repo.StackFrame{
+			Function:    f.Function,
+			Module:      f.Module,
+			Filename:    f.Filename,
+			Line:        f.Line,
+			Column:      f.Column,
+			InApp:       f.InApp,
+			PreContext:  f.PreContext,
+			ContextLine: f.ContextLine,
+			PostContext: f.PostContext,
+		})
+	}
+
+	return st
+}
+
+func toFingerprintFrames(frames []model.StackFrame) []util.FingerprintFrame {
+	ff := make([]util.FingerprintFrame, 0, len(frames))
+
+	for _, f := range frames {
+		ff = append(ff, util.FingerprintFrame{
+			Function: f.Function,
+			Module:   f.Module,
+			Filename: f.Filename,
+			InApp:    f.InApp,
+		})
+	}
+
+	return ff
+}
+
+func toModelStackTrace(frames []repo.StackFrame) []model.StackFrame {
+	st := make([]model.StackFrame, 0, len(frames))
+
+	for _, f := range frames {
+		st = append(st, model.StackFrame{
+			Function:    f.Function,
+			Module:      f.Module,
+			Filename:    f.Filename,
+			Line:        f.Line,
+			Column:      f.Column,
+			InApp:       f.InApp,
+			PreContext:  f.PreContext,
+			ContextLine: f.ContextLine,
+			PostContext: f.PostContext,
+		})
+	}
+
+	return st
+}
+
+func toModelEvent(e *repo.Error) *model.ResponseEvent {
+	return &model.ResponseEvent{
+		ID:                 e.ID.Hex(),
+		ProjectID:          e.ProjectID.Hex(),
+		IssueID:            e.IssueID.Hex(),
+		Message:            e.Message,
+		Type:               e.Type,
+		Fingerprint:        e.Fingerprint,
+		FingerprintVersion: e.FingerprintVersion,
+		StackTrace:         toModelStackTrace(e.StackTrace),
+		Context:            e.Context,
+		Release:            e.Release,
+		Environment:        e.Environment,
+		Tags:               toModelTags(e.Tags),
+		Level:              eventLevel(e.Level),
+		Exception:          toModelException(e.Exception),
+		User:               toModelUser(e.User),
+		Request:            toModelRequest(e.Request),
+		Runtime:            toModelRuntime(e.Runtime),
+		OS:                 toModelOS(e.OS),
+		Device:             toModelDevice(e.Device),
+		Extra:              toModelExtra(e.Extra),
+		Breadcrumbs:        toModelBreadcrumbs(e.Breadcrumbs),
+		Timestamp:          e.Timestamp,
+	}
+}
+
+func toModelException(e *repo.Exception) *model.Exception {
+	if e == nil {
+		return nil
+	}
+
+	return &model.Exception{Type: e.Type, Value: e.Value}
+}
+
+// eventLevel returns level, or error for the events stored before events
+// had a level.
+func eventLevel(level string) string {
+	if level == "" {
+		return model.LevelError
+	}
+
+	return level
+}
+
+func encodeEventCursor(e repo.Error) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(e.Timestamp.UnixNano(), 10) + ":" + e.ID.Hex()))
+}
+
+func decodeEventCursor(cursor string) (*repo.ErrorCursor, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidIssueQuery)
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+
+	ts, hex, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return nil, invalid
+	}
+
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	id, err := bson.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &repo.ErrorCursor{Timestamp: time.Unix(0, nanos), ID: id}, nil
+}
+
+// normalizeEnvironment trims an environment name and rejects the ones that
+// can't be used as a key of the per-environment issue statistics.
+func normalizeEnvironment(environment string) (string, error) {
+	environment = strings.TrimSpace(environment)
+
+	if len(environment) > maxEnvironmentLength {
+		return "", fmt.Errorf("environment must have at most %d characters", maxEnvironmentLength)
+	}
+	if strings.ContainsAny(environment, ".$/\n") {
+		return "", fmt.Errorf("environment must not contain '.', '$', '/' or newlines")
+	}
+
+	return environment, nil
+}
+
+// toRepoTags validates tags and converts them to key/value pairs sorted by
+// key.
+func toRepoTags(tags map[string]string) ([]repo.Tag, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+
+	t := make([]repo.Tag, 0, len(tags))
+
+	for k, v := range tags {
+		if len(k) > maxTagKeyLength || !tagKeyPattern.MatchString(k) {
+			return nil, fmt.Errorf("invalid tag key %q", k)
+		}
+		if v == "" || len(v) > maxTagValueLength {
+			return nil, fmt.Errorf("tag %q must have a value of at most %d characters", k, maxTagValueLength)
+		}
+
+		t = append(t, repo.Tag{Key: k, Value: v})
+	}
+
+	slices.SortFunc(t, func(a, b repo.Tag) int { return strings.Compare(a.Key, b.Key) })
+
+	return t, nil
+}
+
+func toModelTags(tags []repo.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	t := make(map[string]string, len(tags))
+
+	for _, tag := range tags {
+		t[tag.Key] = tag.Value
+	}
+
+	return t
 }
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	model "github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// filteredHeaders are request headers whose values are never stored.
+var filteredHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"X-Bugfy-Key":   true,
+	"X-Sentry-Auth": true,
+}
+
+func toRepoUser(u *model.EventUser) *repo.EventUser {
+	if u == nil || (u.ID == "" && u.Email == "" && u.Username == "" && u.IPAddress == "") {
+		return nil
+	}
+
+	return &repo.EventUser{
+		ID:        u.ID,
+		Email:     u.Email,
+		IPAddress: u.IPAddress,
+		Username:  u.Username,
+	}
+}
+
+// userKey identifies a user for counting the users affected by an issue.
+func userKey(u *repo.EventUser) string {
+	switch {
+	case u == nil:
+		return ""
+	case u.ID != "":
+		return "id:" + u.ID
+	case u.Email != "":
+		return "email:" + u.Email
+	case u.Username != "":
+		return "username:" + u.Username
+	case u.IPAddress != "":
+		return "ip:" + u.IPAddress
+	}
+
+	return ""
+}
+
+func toRepoRequest(r *model.EventRequest) *repo.EventRequest {
+	if r == nil {
+		return nil
+	}
+
+	var headers map[string]string
+	if len(r.Headers) > 0 {
+		headers = make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			if filteredHeaders[http.CanonicalHeaderKey(k)] {
+				v = "[Filtered]"
+			}
+			headers[k] = v
+		}
+	}
+
+	return &repo.EventRequest{
+		URL:     r.URL,
+		Method:  r.Method,
+		Headers: headers,
+		Query:   r.Query,
+		Body:    boundData(r.Body, maxRequestBody),
+	}
+}
+
+func toRepoRuntime(r *model.RuntimeContext) *repo.RuntimeContext {
+	if r == nil {
+		return nil
+	}
+
+	return &repo.RuntimeContext{Name: r.Name, Version: r.Version}
+}
+
+func toRepoOS(o *model.OSContext) *repo.OSContext {
+	if o == nil {
+		return nil
+	}
+
+	return &repo.OSContext{Name: o.Name, Version: o.Version, Build: o.Build}
+}
+
+func toRepoDevice(d *model.DeviceContext) *repo.DeviceContext {
+	if d == nil {
+		return nil
+	}
+
+	return &repo.DeviceContext{Family: d.Family, Model: d.Model, Arch: d.Arch}
+}
+
+func toModelUser(u *repo.EventUser) *model.EventUser {
+	if u == nil {
+		return nil
+	}
+
+	return &model.EventUser{
+		ID:        u.ID,
+		Email:     u.Email,
+		IPAddress: u.IPAddress,
+		Username:  u.Username,
+	}
+}
+
+func toModelRequest(r *repo.EventRequest) *model.EventRequest {
+	if r == nil {
+		return nil
+	}
+
+	return &model.EventRequest{
+		URL:     r.URL,
+		Method:  r.Method,
+		Headers: r.Headers,
+		Query:   r.Query,
+		Body:    toJSONValue(r.Body),
+	}
+}
+
+func toModelRuntime(r *repo.RuntimeContext) *model.RuntimeContext {
+	if r == nil {
+		return nil
+	}
+
+	return &model.RuntimeContext{Name: r.Name, Version: r.Version}
+}
+
+func toModelOS(o *repo.OSContext) *model.OSContext {
+	if o == nil {
+		return nil
+	}
+
+	return &model.OSContext{Name: o.Name, Version: o.Version, Build: o.Build}
+}
+
+func toModelDevice(d *repo.DeviceContext) *model.DeviceContext {
+	if d == nil {
+		return nil
+	}
+
+	return &model.DeviceContext{Family: d.Family, Model: d.Model, Arch: d.Arch}
+}
+
+func toModelExtra(extra bson.M) map[string]any {
+	if len(extra) == 0 {
+		return nil
+	}
+
+	return toJSONValue(extra).(map[string]any)
+}
+
+// toJSONValue converts the documents and arrays decoded from BSON into
+// maps and slices, so nested data is encoded as plain JSON.
+func toJSONValue(v any) any {
+	switch v := v.(type) {
+	case bson.D:
+		m := make(map[string]any, len(v))
+		for _, e := range v {
+			m[e.Key] = toJSONValue(e.Value)
+		}
+		return m
+	case bson.M:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = toJSONValue(e)
+		}
+		return m
+	case bson.A:
+		a := make([]any, 0, len(v))
+		for _, e := range v {
+			a = append(a, toJSONValue(e))
+		}
+		return a
+	}
+
+	return v
+}
+
+// toRepoBreadcrumbs keeps the most recent maxBreadcrumbs breadcrumbs,
+// truncating long categories and messages, blanking unknown levels and
+// dropping data larger than maxBreadcrumbData. Breadcrumbs are auxiliary,
+// so they never fail an event. Breadcrumbs without a timestamp are given the
+// time of the event.
+func toRepoBreadcrumbs(breadcrumbs []model.Breadcrumb, at time.Time) []repo.Breadcrumb {
+	if len(breadcrumbs) > maxBreadcrumbs {
+		breadcrumbs = breadcrumbs[len(breadcrumbs)-maxBreadcrumbs:]
+	}
+
+	b := make([]repo.Breadcrumb, 0, len(breadcrumbs))
+
+	for _, crumb := range breadcrumbs {
+		level := crumb.Level
+		if model.LevelRank(level) == 0 {
+			level = ""
+		}
+
+		timestamp := crumb.Timestamp
+		if timestamp.IsZero() {
+			timestamp = at
+		}
+
+		b = append(b, repo.Breadcrumb{
+			Timestamp: timestamp,
+			Category:  truncate(crumb.Category, maxCategoryLength),
+			Level:     level,
+			Message:   truncate(crumb.Message, maxMessageLength),
+			Data:      boundData(crumb.Data, maxBreadcrumbData),
+		})
+	}
+
+	return b
+}
+
+// boundData returns data, or its zero value when its JSON encoding is
+// larger than limit bytes.
+func boundData[T any](data T, limit int) T {
+	encoded, err := json.Marshal(data)
+	if err != nil || len(encoded) > limit {
+		var zero T
+		return zero
+	}
+
+	return data
+}
+
+func toModelBreadcrumbs(breadcrumbs []repo.Breadcrumb) []model.Breadcrumb {
+	if len(breadcrumbs) == 0 {
+		return nil
+	}
+
+	b := make([]model.Breadcrumb, 0, len(breadcrumbs))
+
+	for _, crumb := range breadcrumbs {
+		var data map[string]any
+		if len(crumb.Data) > 0 {
+			data = toJSONValue(crumb.Data).(map[string]any)
+		}
+
+		b = append(b, model.Breadcrumb{
+			Timestamp: crumb.Timestamp,
+			Category:  crumb.Category,
+			Level:     crumb.Level,
+			Message:   crumb.Message,
+			Data:      data,
+		})
+	}
+
+	return b
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8
+// sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"regexp"
+	"sync"
+
+	model "github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// groupingRule is a project grouping rule with its pattern compiled. A
+// pattern that no longer compiles leaves re nil, and the rule never matches.
+type groupingRule struct {
+	repo.GroupingRule
+	re *regexp.Regexp
+}
+
+type compiledGroupingRules struct {
+	version int
+	rules   []groupingRule
+}
+
+// groupingRuleCache holds the compiled grouping rules of each project, so
+// their patterns are compiled once per version of the rules rather than for
+// every ingested event.
+type groupingRuleCache struct {
+	mu       sync.Mutex
+	projects map[bson.ObjectID]compiledGroupingRules
+}
+
+func newGroupingRuleCache() *groupingRuleCache {
+	return &groupingRuleCache{projects: map[bson.ObjectID]compiledGroupingRules{}}
+}
+
+// rules returns the compiled grouping rules of project, compiling them if
+// the cached ones are missing or of another version.
+func (c *groupingRuleCache) rules(project *repo.Project) []groupingRule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.projects[project.ID]
+	if ok && cached.version == project.GroupingRulesVersion {
+		return cached.rules
+	}
+
+	rules := compileGroupingRules(project.GroupingRules)
+	c.projects[project.ID] = compiledGroupingRules{version: project.GroupingRulesVersion, rules: rules}
+
+	return rules
+}
+
+func compileGroupingRules(rules []repo.GroupingRule) []groupingRule {
+	compiled := make([]groupingRule, 0, len(rules))
+
+	for _, rule := range rules {
+		re, _ := regexp.Compile(rule.Pattern)
+		compiled = append(compiled, groupingRule{GroupingRule: rule, re: re})
+	}
+
+	return compiled
+}
+
+// groupingKey returns the grouping key of e. The first project rule
+// matching e takes precedence over the fingerprint sent by the client,
+// which takes precedence over defaultKey.
+func groupingKey(rules []groupingRule, e *repo.Error, fingerprint []string, defaultKey string) string {
+	for _, rule := range rules {
+		if matchGroupingRule(rule, e) {
+			return util.ExpandFingerprint(rule.Fingerprint, defaultKey)
+		}
+	}
+
+	return util.ExpandFingerprint(fingerprint, defaultKey)
+}
+
+// matchGroupingRule reports whether a project grouping rule applies to e.
+func matchGroupingRule(rule groupingRule, e *repo.Error) bool {
+	if rule.re == nil {
+		return false
+	}
+
+	switch rule.Matcher {
+	case model.GroupingMatcherMessage:
+		return rule.re.MatchString(e.Message)
+	case model.GroupingMatcherContext:
+		v, ok := e.Context[rule.Key]
+		return ok && rule.re.MatchString(v)
+	case model.GroupingMatcherModule:
+		for _, f := range e.StackTrace {
+			if f.Module != "" && rule.re.MatchString(f.Module) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
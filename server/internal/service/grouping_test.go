@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+
+	model "github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestGroupingKeyPrecedence(t *testing.T) {
+	const defaultKey = "0123456789abcdef"
+
+	rules := compileGroupingRules([]repo.GroupingRule{
+		{Matcher: model.GroupingMatcherMessage, Pattern: "^timeout", Fingerprint: []string{"timeouts"}},
+		{Matcher: model.GroupingMatcherContext, Key: "tenant", Pattern: ".*", Fingerprint: []string{"{{ default }}", "tenant"}},
+		{Matcher: model.GroupingMatcherModule, Pattern: `^vendor\.`, Fingerprint: []string{"vendor"}},
+		{Matcher: model.GroupingMatcherMessage, Pattern: "(", Fingerprint: []string{"invalid"}},
+	})
+
+	key := func(parts ...string) string { return util.ExpandFingerprint(parts, defaultKey) }
+
+	tests := []struct {
+		name        string
+		event       repo.Error
+		fingerprint []string
+		want        string
+	}{
+		{
+			"no rule or fingerprint",
+			repo.Error{Message: "boom"},
+			nil,
+			defaultKey,
+		},
+		{
+			"client fingerprint",
+			repo.Error{Message: "boom"},
+			[]string{"client"},
+			key("client"),
+		},
+		{
+			"rule overrides client fingerprint",
+			repo.Error{Message: "timeout after 5s"},
+			[]string{"client"},
+			key("timeouts"),
+		},
+		{
+			"first matching rule wins",
+			repo.Error{Message: "timeout after 5s", Context: map[string]string{"tenant": "acme"}},
+			nil,
+			key("timeouts"),
+		},
+		{
+			"context rule",
+			repo.Error{Message: "boom", Context: map[string]string{"tenant": "acme"}},
+			nil,
+			key("{{ default }}", "tenant"),
+		},
+		{
+			"module rule",
+			repo.Error{Message: "boom", StackTrace: []repo.StackFrame{{Module: "app.main"}, {Module: "vendor.http"}}},
+			nil,
+			key("vendor"),
+		},
+		{
+			"invalid pattern never matches",
+			repo.Error{Message: "("},
+			nil,
+			defaultKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupingKey(rules, &tt.event, tt.fingerprint, defaultKey); got != tt.want {
+				t.Errorf("groupingKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupingRuleCache(t *testing.T) {
+	cache := newGroupingRuleCache()
+	project := &repo.Project{
+		ID:            bson.NewObjectID(),
+		GroupingRules: []repo.GroupingRule{{Matcher: model.GroupingMatcherMessage, Pattern: "a"}},
+	}
+
+	first := cache.rules(project)
+	if len(first) != 1 || first[0].re == nil {
+		t.Fatalf("expected one compiled rule, got %+v", first)
+	}
+
+	if again := cache.rules(project); again[0].re != first[0].re {
+		t.Errorf("expected the rules to be reused for the same version")
+	}
+
+	project.GroupingRules = []repo.GroupingRule{{Matcher: model.GroupingMatcherMessage, Pattern: "b"}}
+	project.GroupingRulesVersion++
+
+	updated := cache.rules(project)
+	if len(updated) != 1 || updated[0].re.String() != "b" {
+		t.Errorf("expected the rules to be recompiled for a new version, got %+v", updated)
+	}
+}
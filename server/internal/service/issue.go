@@ -2,23 +2,47 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dorianneto/bugfy/internal/api/model"
 	repo "github.com/dorianneto/bugfy/internal/repository"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrIssueNotFound      = errors.New("issue not found")
+	ErrInvalidIssueQuery  = errors.New("invalid issue query")
+	ErrInvalidIssueUpdate = errors.New("invalid issue update")
+)
+
+const (
+	maxTopTagValues   = 10
+	maxBulkIssues     = 100
+	defaultIssueLimit = 25
+	maxIssueLimit     = 100
 )
 
 type IssueService struct {
-	issueRepo *repo.IssueRepository
-	timeout   time.Duration
+	issueRepo      *repo.IssueRepository
+	activityRepo   *repo.ActivityRepository
+	errorRepo      *repo.ErrorRepository
+	releaseService *ReleaseService
+	timeout        time.Duration
 }
 
-func NewIssueService(issueRepo *repo.IssueRepository) *IssueService {
+func NewIssueService(issueRepo *repo.IssueRepository, activityRepo *repo.ActivityRepository, errorRepo *repo.ErrorRepository, releaseService *ReleaseService) *IssueService {
 	return &IssueService{
-		issueRepo: issueRepo,
-		timeout:   time.Duration(2) * time.Second,
+		issueRepo:      issueRepo,
+		activityRepo:   activityRepo,
+		errorRepo:      errorRepo,
+		releaseService: releaseService,
+		timeout:        time.Duration(2) * time.Second,
 	}
 }
 
@@ -28,64 +52,855 @@ func (s *IssueService) GroupError(ctx context.Context, e *repo.Error) error {
 
 	log.Printf("IssueService.GroupError - Starting group error creation for fingerprint: %s", e.Fingerprint)
 
-	var issue *repo.Issue
-
-	issue = &repo.Issue{
-		ProjectID:   e.ProjectID,
-		Fingerprint: e.Fingerprint,
-		Title:       e.Message,
-		Count:       1,
-		FirstSeen:   e.Timestamp,
-		LastSeen:    e.Timestamp,
-		Status:      model.IssueStateUnresolved,
+	issue := &repo.Issue{
+		ProjectID:          e.ProjectID,
+		Fingerprint:        e.Fingerprint,
+		FingerprintVersion: e.FingerprintVersion,
+		Title:              e.Message,
+		FirstSeen:          e.Timestamp,
+		LastSeen:           e.Timestamp,
+		Status:             model.IssueStateUnresolved,
+		LevelRank:          model.LevelRank(e.Level),
+	}
+	if e.Exception != nil {
+		issue.ExceptionType = e.Exception.Type
+	}
+	if e.Environment != "" {
+		issue.Environments = []string{e.Environment}
+	}
+	if e.Release != "" {
+		issue.Releases = []string{e.Release}
 	}
 
-	i, err := s.issueRepo.FindIssue(ctx, e.Fingerprint)
+	i, err := s.issueRepo.UpsertIssue(ctx, issue)
 	if err != nil {
 		log.Printf("IssueService.GroupError - Database error: %v", err)
-		return fmt.Errorf("failed to find issue: %v", err)
+		return fmt.Errorf("failed to upsert issue: %v", err)
 	}
 
-	if i != nil {
-		issue = i
-
-		issue.Count++
-		issue.LastSeen = e.Timestamp
+	if !i.MergedInto.IsZero() {
+		primary, err := s.issueRepo.RecordOccurrence(ctx, i.MergedInto, issue)
+		if err != nil {
+			log.Printf("IssueService.GroupError - Database error: %v", err)
+			return fmt.Errorf("failed to upsert issue: %v", err)
+		}
+		if primary != nil {
+			i = primary
+		}
 	}
 
-	_, err = s.issueRepo.UpsertIssue(ctx, issue)
+	err = s.errorRepo.SetErrorIssue(ctx, e.ID, i.ID)
 	if err != nil {
 		log.Printf("IssueService.GroupError - Database error: %v", err)
-		return fmt.Errorf("failed to upsert issue: %v", err)
+		return fmt.Errorf("failed to link error to issue: %v", err)
+	}
+	e.IssueID = i.ID
+
+	if e.UserKey != "" {
+		if err := s.issueRepo.AddIssueUser(ctx, i.ID, e.UserKey, e.Timestamp); err != nil {
+			log.Printf("IssueService.GroupError - Database error: %v", err)
+		}
+	}
+
+	switch {
+	case i.Status == model.IssueStateResolved && s.reachedRelease(ctx, i, e):
+		s.regressIssue(ctx, i, e)
+	case i.Status == model.IssueStateIgnored && i.Ignore != nil:
+		s.checkIgnore(ctx, i, e)
 	}
 
 	return nil
 }
 
-func (s *IssueService) GetIssues(ctx context.Context, projectId string) ([]model.ResponseGetIssues, error) {
+// reachedRelease reports whether e is recent enough to reopen an issue
+// resolved in a release. Events without a release can't be placed relative
+// to it, so they don't reopen the issue.
+func (s *IssueService) reachedRelease(ctx context.Context, issue *repo.Issue, e *repo.Error) bool {
+	if issue.ResolvedInRelease == "" {
+		return true
+	}
+	if e.Release == "" {
+		return false
+	}
+
+	ok, err := s.releaseService.IsAtLeast(ctx, issue.ProjectID, e.Release, issue.ResolvedInRelease)
+	if err != nil {
+		log.Printf("IssueService.reachedRelease - Database error: %v", err)
+		return false
+	}
+
+	return ok
+}
+
+// regressIssue reopens a resolved issue that received a new event.
+func (s *IssueService) regressIssue(ctx context.Context, issue *repo.Issue, e *repo.Error) {
+	i, err := s.issueRepo.MarkRegression(ctx, issue.ID, e.ID, e.Timestamp)
+	if err != nil {
+		log.Printf("IssueService.regressIssue - Database error: %v", err)
+		return
+	}
+	if i == nil {
+		return
+	}
+
+	log.Printf("IssueService.regressIssue - Issue %s regressed by event %s", i.ID.Hex(), e.ID.Hex())
+
+	s.recordActivity(ctx, &repo.Activity{
+		IssueID:   i.ID,
+		ProjectID: i.ProjectID,
+		Type:      model.ActivitySetRegression,
+		Data:      map[string]string{"event_id": e.ID.Hex()},
+		CreatedAt: e.Timestamp,
+	})
+}
+
+// GetIssues returns one page of a project's issues along with the cursor
+// of the next page, which is empty on the last page.
+func (s *IssueService) GetIssues(ctx context.Context, projectId string, req model.RequestGetIssues) ([]model.ResponseGetIssues, string, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	i, err := s.issueRepo.FindIssuesByProject(ctx, projectId)
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid project id: %v", err)
+	}
+
+	filter, err := parseIssueFilter(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var values map[string]string
+
+	if req.Query != "" {
+		q, err := parseIssueQuery(req.Query)
+		if err != nil {
+			return nil, "", err
+		}
+		q.apply(&filter)
+		values = q.EventValues
+	}
+
+	if len(req.Tags) > 0 || len(values) > 0 {
+		filter.IDs, err = s.errorRepo.FindIssueIDs(ctx, pID, req.Tags, values)
+		if err != nil {
+			log.Printf("IssueService.GetIssues - Database error: %v", err)
+			return nil, "", fmt.Errorf("failed to search events: %v", err)
+		}
+		if filter.IDs == nil {
+			filter.IDs = []bson.ObjectID{}
+		}
+	}
+
+	sort := req.Sort
+	if sort == "" {
+		sort = model.IssueSortLastSeen
+	}
+	if sort != model.IssueSortLastSeen && sort != model.IssueSortFirstSeen && sort != model.IssueSortCount {
+		return nil, "", fmt.Errorf("%w: unknown sort %q", ErrInvalidIssueQuery, sort)
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultIssueLimit
+	}
+	if limit < 0 || limit > maxIssueLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidIssueQuery, maxIssueLimit)
+	}
+
+	var after *repo.IssueCursor
+	if req.Cursor != "" {
+		after, err = decodeIssueCursor(req.Cursor, sort)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// One extra issue is fetched to tell whether there is a next page.
+	i, err := s.issueRepo.FindIssues(ctx, pID, filter, sort, after, limit+1)
 	if err != nil {
 		log.Printf("IssueService.GetIssues - Database error: %v", err)
-		return nil, fmt.Errorf("failed to fetch issues: %v", err)
+		return nil, "", fmt.Errorf("failed to fetch issues: %v", err)
+	}
+
+	next := ""
+	if len(i) > limit {
+		i = i[:limit]
+		next = encodeIssueCursor(i[limit-1], sort)
 	}
 
 	issues := make([]model.ResponseGetIssues, 0, len(i))
 
 	for _, issue := range i {
-		issues = append(issues, model.ResponseGetIssues{
-			ID:          issue.ID.Hex(),
-			ProjectID:   issue.ProjectID.Hex(),
-			Title:       issue.Title,
-			Fingerprint: issue.Fingerprint,
-			Count:       issue.Count,
-			FirstSeen:   issue.FirstSeen,
-			LastSeen:    issue.LastSeen,
-			Status:      issue.Status,
-		})
+		issues = append(issues, toModelIssue(issue))
+	}
+
+	return issues, next, nil
+}
+
+func (s *IssueService) UpdateIssueStatus(ctx context.Context, projectId string, issueId string, userID string, req model.RequestUpdateIssue) (*model.ResponseGetIssues, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("IssueService.UpdateIssueStatus - Setting issue %s to %s", issueId, req.Status)
+
+	status, ignore, release, err := parseStatusUpdate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pID, uID, err := parseProjectAndUser(projectId, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	iID, err := bson.ObjectIDFromHex(issueId)
+	if err != nil {
+		return nil, ErrIssueNotFound
+	}
+
+	issue, err := s.setIssueStatus(ctx, pID, iID, status, ignore, release, uID)
+	if err != nil {
+		return nil, err
+	}
+
+	i := toModelIssue(*issue)
+
+	return &i, nil
+}
+
+// BulkUpdateIssueStatus applies one status to several issues of a project.
+// Issues that don't exist are skipped; the updated issues are returned.
+func (s *IssueService) BulkUpdateIssueStatus(ctx context.Context, projectId string, userID string, req model.RequestBulkUpdateIssues) ([]model.ResponseGetIssues, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("IssueService.BulkUpdateIssueStatus - Setting %d issues to %s", len(req.IDs), req.Status)
+
+	status, ignore, release, err := parseStatusUpdate(req.RequestUpdateIssue)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkIssues {
+		return nil, fmt.Errorf("%w: between 1 and %d issue ids are required", ErrInvalidIssueUpdate, maxBulkIssues)
+	}
+
+	pID, uID, err := parseProjectAndUser(projectId, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]model.ResponseGetIssues, 0, len(req.IDs))
+
+	for _, id := range req.IDs {
+		iID, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+
+		issue, err := s.setIssueStatus(ctx, pID, iID, status, ignore, release, uID)
+		if errors.Is(err, ErrIssueNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, toModelIssue(*issue))
 	}
 
 	return issues, nil
 }
+
+func (s *IssueService) GetIssueActivity(ctx context.Context, projectId string, issueId string) ([]model.ResponseIssueActivity, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	issue, err := s.findIssue(ctx, projectId, issueId)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.activityRepo.FindActivitiesByIssue(ctx, issue.ID)
+	if err != nil {
+		log.Printf("IssueService.GetIssueActivity - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch activity: %v", err)
+	}
+
+	activities := make([]model.ResponseIssueActivity, 0, len(a))
+
+	for _, activity := range a {
+		userID := ""
+		if !activity.UserID.IsZero() {
+			userID = activity.UserID.Hex()
+		}
+
+		activities = append(activities, model.ResponseIssueActivity{
+			ID:        activity.ID.Hex(),
+			IssueID:   activity.IssueID.Hex(),
+			UserID:    userID,
+			Type:      activity.Type,
+			Data:      activity.Data,
+			CreatedAt: activity.CreatedAt,
+		})
+	}
+
+	return activities, nil
+}
+
+// MergeIssues merges the issues with req.IDs into the issue with
+// req.PrimaryID, moving their events to it.
+func (s *IssueService) MergeIssues(ctx context.Context, projectId string, userID string, req model.RequestMergeIssues) (*model.ResponseGetIssues, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("IssueService.MergeIssues - Merging %d issues into %s", len(req.IDs), req.PrimaryID)
+
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkIssues {
+		return nil, fmt.Errorf("%w: between 1 and %d issue ids are required", ErrInvalidIssueUpdate, maxBulkIssues)
+	}
+
+	pID, uID, err := parseProjectAndUser(projectId, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := s.findIssue(ctx, projectId, req.PrimaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{req.PrimaryID: true}
+	secondaries := make([]repo.Issue, 0, len(req.IDs))
+	ids := make([]bson.ObjectID, 0, len(req.IDs))
+
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		issue, err := s.findIssue(ctx, projectId, id)
+		if err != nil {
+			return nil, err
+		}
+
+		secondaries = append(secondaries, *issue)
+		ids = append(ids, issue.ID)
+	}
+
+	if len(secondaries) == 0 {
+		return nil, fmt.Errorf("%w: at least one issue other than the primary is required", ErrInvalidIssueUpdate)
+	}
+
+	err = s.issueRepo.MergeIssues(ctx, primary.ID, secondaries)
+	if err != nil {
+		log.Printf("IssueService.MergeIssues - Database error: %v", err)
+		return nil, fmt.Errorf("failed to merge issues: %v", err)
+	}
+
+	err = s.errorRepo.MoveErrors(ctx, ids, "", primary.ID)
+	if err != nil {
+		log.Printf("IssueService.MergeIssues - Database error: %v", err)
+		return nil, fmt.Errorf("failed to move events: %v", err)
+	}
+
+	// The statistics are recomputed from the moved events rather than
+	// summed, so events grouped while merging are not lost.
+	stats, err := s.computeStats(ctx, primary)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.issueRepo.SetIssueStats(ctx, primary.ID, stats)
+	if err != nil {
+		log.Printf("IssueService.MergeIssues - Database error: %v", err)
+		return nil, fmt.Errorf("failed to update issue stats: %v", err)
+	}
+
+	err = s.issueRepo.MergeIssueUsers(ctx, ids, primary.ID)
+	if err != nil {
+		log.Printf("IssueService.MergeIssues - Database error: %v", err)
+		return nil, fmt.Errorf("failed to merge users: %v", err)
+	}
+
+	hexIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hexIDs = append(hexIDs, id.Hex())
+	}
+
+	s.recordActivity(ctx, &repo.Activity{
+		IssueID:   primary.ID,
+		ProjectID: pID,
+		UserID:    uID,
+		Type:      model.ActivityMerge,
+		Data:      map[string]string{"issues": strings.Join(hexIDs, ",")},
+		CreatedAt: time.Now(),
+	})
+
+	return s.getIssue(ctx, pID, primary.ID)
+}
+
+// UnmergeIssue splits req.Fingerprint back out of the issue it was merged
+// into, along with its events, and returns the restored issue. The
+// statistics of both issues are recomputed from their events.
+func (s *IssueService) UnmergeIssue(ctx context.Context, projectId string, issueId string, userID string, req model.RequestUnmergeIssue) (*model.ResponseGetIssues, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("IssueService.UnmergeIssue - Unmerging fingerprint %s from issue %s", req.Fingerprint, issueId)
+
+	pID, uID, err := parseProjectAndUser(projectId, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := s.findIssue(ctx, projectId, issueId)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := s.issueRepo.FindIssue(ctx, pID, req.Fingerprint)
+	if err != nil {
+		log.Printf("IssueService.UnmergeIssue - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch issue: %v", err)
+	}
+	if merged == nil || merged.MergedInto != primary.ID {
+		return nil, fmt.Errorf("%w: fingerprint %q is not merged into this issue", ErrInvalidIssueUpdate, req.Fingerprint)
+	}
+
+	err = s.errorRepo.MoveErrors(ctx, []bson.ObjectID{primary.ID}, req.Fingerprint, merged.ID)
+	if err != nil {
+		log.Printf("IssueService.UnmergeIssue - Database error: %v", err)
+		return nil, fmt.Errorf("failed to move events: %v", err)
+	}
+
+	primaryStats, err := s.computeStats(ctx, primary)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedStats, err := s.computeStats(ctx, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.issueRepo.UnmergeIssue(ctx, primary.ID, primaryStats, merged.ID, req.Fingerprint, mergedStats)
+	if err != nil {
+		log.Printf("IssueService.UnmergeIssue - Database error: %v", err)
+		return nil, fmt.Errorf("failed to unmerge issue: %v", err)
+	}
+
+	for _, id := range []bson.ObjectID{primary.ID, merged.ID} {
+		if err := s.issueRepo.RebuildIssueUsers(ctx, id); err != nil {
+			log.Printf("IssueService.UnmergeIssue - Database error: %v", err)
+			return nil, fmt.Errorf("failed to recount users: %v", err)
+		}
+	}
+
+	now := time.Now()
+	for _, id := range []bson.ObjectID{primary.ID, merged.ID} {
+		s.recordActivity(ctx, &repo.Activity{
+			IssueID:   id,
+			ProjectID: pID,
+			UserID:    uID,
+			Type:      model.ActivityUnmerge,
+			Data:      map[string]string{"fingerprint": req.Fingerprint, "issue_id": merged.ID.Hex()},
+			CreatedAt: now,
+		})
+	}
+
+	return s.getIssue(ctx, pID, merged.ID)
+}
+
+// computeStats recomputes the statistics of issue from its events. An
+// issue without events keeps its first and last seen times.
+func (s *IssueService) computeStats(ctx context.Context, issue *repo.Issue) (repo.IssueStats, error) {
+	stats, err := s.errorRepo.ComputeIssueStats(ctx, issue.ID)
+	if err != nil {
+		log.Printf("IssueService.computeStats - Database error: %v", err)
+		return repo.IssueStats{}, fmt.Errorf("failed to compute issue stats: %v", err)
+	}
+	if stats == nil {
+		return repo.IssueStats{FirstSeen: issue.FirstSeen, LastSeen: issue.LastSeen}, nil
+	}
+
+	return *stats, nil
+}
+
+func (s *IssueService) getIssue(ctx context.Context, projectID bson.ObjectID, issueID bson.ObjectID) (*model.ResponseGetIssues, error) {
+	issue, err := s.issueRepo.FindIssueByID(ctx, projectID, issueID)
+	if err != nil {
+		log.Printf("IssueService.getIssue - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch issue: %v", err)
+	}
+	if issue == nil {
+		return nil, ErrIssueNotFound
+	}
+
+	i := toModelIssue(*issue)
+
+	return &i, nil
+}
+
+// GetIssueTags returns, for each tag key of an issue's events, the number
+// of events carrying it and its most common values.
+func (s *IssueService) GetIssueTags(ctx context.Context, projectId string, issueId string) ([]model.ResponseTagDistribution, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	issue, err := s.findIssue(ctx, projectId, issueId)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := s.errorRepo.FindTagDistribution(ctx, issue.ID, maxTopTagValues)
+	if err != nil {
+		log.Printf("IssueService.GetIssueTags - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch tags: %v", err)
+	}
+
+	tags := make([]model.ResponseTagDistribution, 0, len(d))
+
+	for _, tag := range d {
+		values := make([]model.ResponseTagValue, 0, len(tag.TopValues))
+		for _, v := range tag.TopValues {
+			values = append(values, model.ResponseTagValue{Value: v.Value, Count: v.Count})
+		}
+
+		tags = append(tags, model.ResponseTagDistribution{
+			Key:       tag.Key,
+			Total:     tag.Total,
+			Unique:    tag.Unique,
+			TopValues: values,
+		})
+	}
+
+	return tags, nil
+}
+
+// setIssueStatus changes the status of an issue and records the change in
+// its activity history. Setting the status an issue already has is a no-op,
+// except for ignoring and resolving in a release, which replace the ignore
+// conditions and the release.
+func (s *IssueService) setIssueStatus(ctx context.Context, projectID bson.ObjectID, issueID bson.ObjectID, status model.IssueState, ignore *repo.IgnoreCondition, release string, userID bson.ObjectID) (*repo.Issue, error) {
+	now := time.Now()
+
+	before, err := s.issueRepo.SetIssueStatus(ctx, projectID, issueID, status, ignore, release, userID, now)
+	if err != nil {
+		log.Printf("IssueService.setIssueStatus - Database error: %v", err)
+		return nil, fmt.Errorf("failed to update issue: %v", err)
+	}
+
+	if before != nil {
+		data := map[string]string{"from": before.Status.String(), "to": status.String()}
+		if ignore != nil {
+			if ignore.Until != nil {
+				data["until"] = ignore.Until.Format(time.RFC3339)
+			}
+			if ignore.Count > 0 {
+				data["count"] = strconv.Itoa(ignore.Count)
+			}
+			if ignore.Window > 0 {
+				data["window"] = strconv.Itoa(ignore.Window)
+			}
+		}
+		if release != "" {
+			data["release"] = release
+		}
+
+		s.recordActivity(ctx, &repo.Activity{
+			IssueID:   issueID,
+			ProjectID: projectID,
+			UserID:    userID,
+			Type:      model.ActivityForState(status),
+			Data:      data,
+			CreatedAt: now,
+		})
+	}
+
+	issue, err := s.issueRepo.FindIssueByID(ctx, projectID, issueID)
+	if err != nil {
+		log.Printf("IssueService.setIssueStatus - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch issue: %v", err)
+	}
+	if issue == nil {
+		return nil, ErrIssueNotFound
+	}
+
+	return issue, nil
+}
+
+// checkIgnore unignores an ignored issue once its ignore condition is met
+// by the event e.
+func (s *IssueService) checkIgnore(ctx context.Context, issue *repo.Issue, e *repo.Error) {
+	c := issue.Ignore
+	reason := ""
+
+	switch {
+	case c.Until != nil && !e.Timestamp.Before(*c.Until):
+		reason = "duration"
+	case c.Count > 0 && c.Window == 0 && issue.Count-c.StartCount >= c.Count:
+		reason = "count"
+	case c.Count > 0 && c.Window > 0:
+		since := e.Timestamp.Add(-time.Duration(c.Window) * time.Minute)
+		if since.Before(c.IgnoredAt) {
+			since = c.IgnoredAt
+		}
+
+		n, err := s.errorRepo.CountErrorsSince(ctx, issue.ID, since)
+		if err != nil {
+			log.Printf("IssueService.checkIgnore - Database error: %v", err)
+			return
+		}
+		if n >= int64(c.Count) {
+			reason = "window"
+		}
+	}
+
+	if reason == "" {
+		return
+	}
+
+	i, err := s.issueRepo.Unignore(ctx, issue.ID, e.Timestamp)
+	if err != nil {
+		log.Printf("IssueService.checkIgnore - Database error: %v", err)
+		return
+	}
+	if i == nil {
+		return
+	}
+
+	log.Printf("IssueService.checkIgnore - Issue %s unignored (%s) by event %s", i.ID.Hex(), reason, e.ID.Hex())
+
+	s.recordActivity(ctx, &repo.Activity{
+		IssueID:   i.ID,
+		ProjectID: i.ProjectID,
+		Type:      model.ActivitySetUnignored,
+		Data:      map[string]string{"reason": reason, "event_id": e.ID.Hex()},
+		CreatedAt: e.Timestamp,
+	})
+}
+
+// recordActivity stores an activity entry. Failures are logged rather than
+// returned since the change it describes has already been applied.
+func (s *IssueService) recordActivity(ctx context.Context, a *repo.Activity) {
+	if _, err := s.activityRepo.CreateActivity(ctx, a); err != nil {
+		log.Printf("IssueService.recordActivity - Database error: %v", err)
+	}
+}
+
+func (s *IssueService) findIssue(ctx context.Context, projectId string, issueId string) (*repo.Issue, error) {
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, ErrIssueNotFound
+	}
+
+	iID, err := bson.ObjectIDFromHex(issueId)
+	if err != nil {
+		return nil, ErrIssueNotFound
+	}
+
+	issue, err := s.issueRepo.FindIssueByID(ctx, pID, iID)
+	if err != nil {
+		log.Printf("IssueService.findIssue - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch issue: %v", err)
+	}
+	if issue == nil || !issue.MergedInto.IsZero() {
+		return nil, ErrIssueNotFound
+	}
+
+	return issue, nil
+}
+
+// parseStatusUpdate validates a status change and builds the ignore
+// condition and the release it describes, if any.
+func parseStatusUpdate(req model.RequestUpdateIssue) (model.IssueState, *repo.IgnoreCondition, string, error) {
+	status, err := model.ParseIssueState(req.Status)
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("%w: %v", ErrInvalidIssueUpdate, err)
+	}
+
+	release, err := normalizeRelease(req.ResolvedInRelease)
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("%w: %v", ErrInvalidIssueUpdate, err)
+	}
+	if release != "" && status != model.IssueStateResolved {
+		return 0, nil, "", fmt.Errorf("%w: resolved_in_release requires the resolved status", ErrInvalidIssueUpdate)
+	}
+
+	if req.IgnoreDuration < 0 || req.IgnoreCount < 0 || req.IgnoreWindow < 0 {
+		return 0, nil, "", fmt.Errorf("%w: ignore conditions must not be negative", ErrInvalidIssueUpdate)
+	}
+
+	if req.IgnoreDuration == 0 && req.IgnoreCount == 0 && req.IgnoreWindow == 0 {
+		return status, nil, release, nil
+	}
+
+	if status != model.IssueStateIgnored {
+		return 0, nil, "", fmt.Errorf("%w: ignore conditions require the ignored status", ErrInvalidIssueUpdate)
+	}
+
+	if req.IgnoreWindow > 0 && req.IgnoreCount == 0 {
+		return 0, nil, "", fmt.Errorf("%w: ignore_window requires ignore_count", ErrInvalidIssueUpdate)
+	}
+
+	ignore := &repo.IgnoreCondition{
+		Count:  req.IgnoreCount,
+		Window: req.IgnoreWindow,
+	}
+
+	if req.IgnoreDuration > 0 {
+		until := time.Now().Add(time.Duration(req.IgnoreDuration) * time.Minute)
+		ignore.Until = &until
+	}
+
+	return status, ignore, "", nil
+}
+
+func parseIssueFilter(req model.RequestGetIssues) (repo.IssueFilter, error) {
+	filter := repo.IssueFilter{
+		FirstSeenFrom: req.FirstSeenFrom,
+		FirstSeenTo:   req.FirstSeenTo,
+		LastSeenFrom:  req.LastSeenFrom,
+		LastSeenTo:    req.LastSeenTo,
+		MinCount:      req.MinCount,
+		Environment:   req.Environment,
+		Release:       req.Release,
+	}
+
+	if req.Level != "" {
+		filter.LevelRank = model.LevelRank(req.Level)
+		if filter.LevelRank == 0 {
+			return filter, fmt.Errorf("%w: unknown level %q", ErrInvalidIssueQuery, req.Level)
+		}
+	}
+
+	if req.Status != "" {
+		status, err := model.ParseIssueState(req.Status)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %v", ErrInvalidIssueQuery, err)
+		}
+		filter.Status = &status
+	}
+
+	if req.MinCount < 0 {
+		return filter, fmt.Errorf("%w: min_count must not be negative", ErrInvalidIssueQuery)
+	}
+
+	return filter, nil
+}
+
+// encodeIssueCursor encodes the position of issue in a listing sorted by
+// sort as an opaque string.
+func encodeIssueCursor(issue repo.Issue, sort string) string {
+	value := strconv.FormatInt(issue.LastSeen.UnixNano(), 10)
+	switch sort {
+	case model.IssueSortFirstSeen:
+		value = strconv.FormatInt(issue.FirstSeen.UnixNano(), 10)
+	case model.IssueSortCount:
+		value = strconv.Itoa(issue.Count)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(sort + ":" + value + ":" + issue.ID.Hex()))
+}
+
+func decodeIssueCursor(cursor string, sort string) (*repo.IssueCursor, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidIssueQuery)
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, invalid
+	}
+
+	value, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	id, err := bson.ObjectIDFromHex(parts[2])
+	if err != nil {
+		return nil, invalid
+	}
+
+	c := &repo.IssueCursor{ID: id}
+	if sort == model.IssueSortCount {
+		c.Count = int(value)
+	} else {
+		c.Time = time.Unix(0, value)
+	}
+
+	return c, nil
+}
+
+func parseProjectAndUser(projectId string, userID string) (bson.ObjectID, bson.ObjectID, error) {
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return bson.ObjectID{}, bson.ObjectID{}, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	uID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return bson.ObjectID{}, bson.ObjectID{}, fmt.Errorf("invalid user id: %v", err)
+	}
+
+	return pID, uID, nil
+}
+
+func toModelIssue(issue repo.Issue) model.ResponseGetIssues {
+	i := model.ResponseGetIssues{
+		ID:                 issue.ID.Hex(),
+		ProjectID:          issue.ProjectID.Hex(),
+		Title:              issue.Title,
+		Fingerprint:        issue.Fingerprint,
+		FingerprintVersion: issue.FingerprintVersion,
+		Count:              issue.Count,
+		FirstSeen:          issue.FirstSeen,
+		LastSeen:           issue.LastSeen,
+		Status:             issue.Status,
+		StatusChangedAt:    issue.StatusChangedAt,
+		Regressed:          issue.Regressed,
+		RegressedAt:        issue.RegressedAt,
+		ResolvedInRelease:  issue.ResolvedInRelease,
+		MergedFingerprints: issue.MergedFingerprints,
+		UserCount:          issue.UserCount,
+		Level:              eventLevel(model.LevelForRank(issue.LevelRank)),
+		ExceptionType:      issue.ExceptionType,
+	}
+
+	if !issue.StatusChangedBy.IsZero() {
+		i.StatusChangedBy = issue.StatusChangedBy.Hex()
+	}
+
+	if issue.Ignore != nil {
+		i.Ignore = &model.ResponseIgnoreCondition{
+			Until:     issue.Ignore.Until,
+			Count:     issue.Ignore.Count,
+			Window:    issue.Ignore.Window,
+			IgnoredAt: issue.Ignore.IgnoredAt,
+		}
+	}
+
+	if !issue.RegressionEventID.IsZero() {
+		i.RegressionEventID = issue.RegressionEventID.Hex()
+	}
+
+	if len(issue.EnvironmentStats) > 0 {
+		i.Environments = make(map[string]model.ResponseEnvironmentStats, len(issue.EnvironmentStats))
+		for env, stats := range issue.EnvironmentStats {
+			i.Environments[env] = model.ResponseEnvironmentStats{
+				Count:     stats.Count,
+				FirstSeen: stats.FirstSeen,
+				LastSeen:  stats.LastSeen,
+			}
+		}
+	}
+
+	return i
+}
@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	model "github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrMemberNotFound       = errors.New("member not found")
+	ErrForbidden            = errors.New("insufficient permissions")
+	ErrInvalidInvite        = errors.New("invalid or expired invite")
+)
+
+const inviteTTL = 7 * 24 * time.Hour
+
+type OrganizationService struct {
+	orgRepo  *repo.OrganizationRepository
+	userRepo *repo.UserRepository
+	timeout  time.Duration
+}
+
+func NewOrganizationService(orgRepo *repo.OrganizationRepository, userRepo *repo.UserRepository) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:  orgRepo,
+		userRepo: userRepo,
+		timeout:  time.Duration(2) * time.Second,
+	}
+}
+
+func (s *OrganizationService) CreateOrganization(ctx context.Context, userID string, req model.RequestCreateOrganization) (*model.ResponseOrganization, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("OrganizationService.CreateOrganization - Starting organization creation for: %s", req.Name)
+
+	if req.Name == "" {
+		log.Printf("OrganizationService.CreateOrganization - Validation failed: missing required fields")
+		return nil, fmt.Errorf("name is required")
+	}
+
+	uID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+
+	org, err := s.orgRepo.CreateOrganization(ctx, &repo.Organization{
+		Name:      req.Name,
+		CreatedBy: uID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("OrganizationService.CreateOrganization - Database error: %v", err)
+		return nil, fmt.Errorf("failed to create organization: %v", err)
+	}
+
+	_, err = s.orgRepo.AddMembership(ctx, &repo.Membership{
+		OrganizationID: org.ID,
+		UserID:         uID,
+		Role:           model.RoleOwner,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("OrganizationService.CreateOrganization - Database error: %v", err)
+		return nil, fmt.Errorf("failed to add organization owner: %v", err)
+	}
+
+	log.Printf("OrganizationService.CreateOrganization - Organization created successfully in database: %s", org.ID.Hex())
+
+	return &model.ResponseOrganization{
+		ID:        org.ID.Hex(),
+		Name:      org.Name,
+		Role:      model.RoleOwner,
+		CreatedAt: org.CreatedAt,
+	}, nil
+}
+
+func (s *OrganizationService) GetOrganizations(ctx context.Context, userID string) ([]model.ResponseOrganization, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	uID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+
+	memberships, err := s.orgRepo.FindMembershipsByUser(ctx, uID)
+	if err != nil {
+		log.Printf("OrganizationService.GetOrganizations - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch organizations: %v", err)
+	}
+
+	orgs := make([]model.ResponseOrganization, 0, len(memberships))
+	if len(memberships) == 0 {
+		return orgs, nil
+	}
+
+	roles := make(map[bson.ObjectID]string, len(memberships))
+	ids := make([]bson.ObjectID, 0, len(memberships))
+
+	for _, m := range memberships {
+		roles[m.OrganizationID] = m.Role
+		ids = append(ids, m.OrganizationID)
+	}
+
+	o, err := s.orgRepo.FindOrganizationsByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("OrganizationService.GetOrganizations - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch organizations: %v", err)
+	}
+
+	for _, org := range o {
+		orgs = append(orgs, model.ResponseOrganization{
+			ID:        org.ID.Hex(),
+			Name:      org.Name,
+			Role:      roles[org.ID],
+			CreatedAt: org.CreatedAt,
+		})
+	}
+
+	return orgs, nil
+}
+
+func (s *OrganizationService) GetMembers(ctx context.Context, orgId string, userID string) ([]model.ResponseMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	membership, err := s.authorizeHex(ctx, orgId, userID, model.RoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.orgRepo.FindMembershipsByOrganization(ctx, membership.OrganizationID)
+	if err != nil {
+		log.Printf("OrganizationService.GetMembers - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch members: %v", err)
+	}
+
+	ids := make([]bson.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		ids = append(ids, m.UserID)
+	}
+
+	u, err := s.userRepo.FindUsersByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("OrganizationService.GetMembers - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch members: %v", err)
+	}
+
+	users := make(map[bson.ObjectID]repo.User, len(u))
+	for _, user := range u {
+		users[user.ID] = user
+	}
+
+	members := make([]model.ResponseMember, 0, len(memberships))
+
+	for _, m := range memberships {
+		members = append(members, toModelMember(m, users[m.UserID]))
+	}
+
+	return members, nil
+}
+
+// UpdateMember changes the role of a member. Admins manage members and
+// admins; only owners may grant or change the owner role. Nobody can change
+// their own membership, which keeps at least one owner in place.
+func (s *OrganizationService) UpdateMember(ctx context.Context, orgId string, userID string, memberId string, req model.RequestUpdateMember) (*model.ResponseMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("OrganizationService.UpdateMember - Updating member %s of organization: %s", memberId, orgId)
+
+	if model.RoleRank(req.Role) == 0 {
+		return nil, fmt.Errorf("unknown role %q", req.Role)
+	}
+
+	target, err := s.manageMember(ctx, orgId, userID, memberId, req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.orgRepo.UpdateMembershipRole(ctx, target.OrganizationID, target.UserID, req.Role)
+	if err != nil {
+		log.Printf("OrganizationService.UpdateMember - Database error: %v", err)
+		return nil, fmt.Errorf("failed to update member: %v", err)
+	}
+	if m == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, m.UserID)
+	if err != nil {
+		log.Printf("OrganizationService.UpdateMember - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch member: %v", err)
+	}
+	if user == nil {
+		user = &repo.User{ID: m.UserID}
+	}
+
+	member := toModelMember(*m, *user)
+
+	return &member, nil
+}
+
+func (s *OrganizationService) RemoveMember(ctx context.Context, orgId string, userID string, memberId string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("OrganizationService.RemoveMember - Removing member %s from organization: %s", memberId, orgId)
+
+	target, err := s.manageMember(ctx, orgId, userID, memberId, model.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	err = s.orgRepo.DeleteMembership(ctx, target.OrganizationID, target.UserID)
+	if err != nil {
+		log.Printf("OrganizationService.RemoveMember - Database error: %v", err)
+		return fmt.Errorf("failed to remove member: %v", err)
+	}
+
+	return nil
+}
+
+func (s *OrganizationService) CreateInvite(ctx context.Context, orgId string, userID string, req model.RequestCreateInvite) (*model.ResponseInvite, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("OrganizationService.CreateInvite - Inviting %s to organization: %s", req.Email, orgId)
+
+	email := normalizeEmail(req.Email)
+	if email == "" {
+		return nil, fmt.Errorf("email is required")
+	}
+
+	if req.Role == "" {
+		req.Role = model.RoleMember
+	}
+	if model.RoleRank(req.Role) == 0 {
+		return nil, fmt.Errorf("unknown role %q", req.Role)
+	}
+
+	membership, err := s.authorizeHex(ctx, orgId, userID, model.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.RoleRank(req.Role) > model.RoleRank(membership.Role) {
+		return nil, ErrForbidden
+	}
+
+	token, err := util.GenerateKey()
+	if err != nil {
+		log.Printf("OrganizationService.CreateInvite - Token generation failed: %v", err)
+		return nil, fmt.Errorf("failed to create invite")
+	}
+
+	invite, err := s.orgRepo.CreateInvite(ctx, &repo.Invite{
+		OrganizationID: membership.OrganizationID,
+		Email:          email,
+		Role:           req.Role,
+		Token:          token,
+		InvitedBy:      membership.UserID,
+		CreatedAt:      time.Now(),
+		ExpiresAt:      time.Now().Add(inviteTTL),
+	})
+	if err != nil {
+		log.Printf("OrganizationService.CreateInvite - Database error: %v", err)
+		return nil, fmt.Errorf("failed to create invite: %v", err)
+	}
+
+	log.Printf("OrganizationService.CreateInvite - Invite created successfully in database: %s", invite.ID.Hex())
+
+	return &model.ResponseInvite{
+		ID:             invite.ID.Hex(),
+		OrganizationID: invite.OrganizationID.Hex(),
+		Email:          invite.Email,
+		Role:           invite.Role,
+		Token:          invite.Token,
+		ExpiresAt:      invite.ExpiresAt,
+	}, nil
+}
+
+// CheckInvite reports ErrInvalidInvite unless token is a pending invite for
+// email. It lets signup reject a bad token before the account is created.
+func (s *OrganizationService) CheckInvite(ctx context.Context, token string, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	invite, err := s.orgRepo.FindPendingInvite(ctx, token, normalizeEmail(email), time.Now())
+	if err != nil {
+		log.Printf("OrganizationService.CheckInvite - Database error: %v", err)
+		return fmt.Errorf("failed to check invite: %v", err)
+	}
+	if invite == nil {
+		return ErrInvalidInvite
+	}
+
+	return nil
+}
+
+// AcceptInvite redeems an invite for the user, who must own the invited
+// email address, and adds them to the organization with the invited role.
+func (s *OrganizationService) AcceptInvite(ctx context.Context, token string, userID string) (*model.ResponseOrganization, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	uID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, uID)
+	if err != nil {
+		log.Printf("OrganizationService.AcceptInvite - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch user: %v", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidInvite
+	}
+
+	invite, err := s.orgRepo.AcceptInvite(ctx, token, normalizeEmail(user.Email), time.Now())
+	if err != nil {
+		log.Printf("OrganizationService.AcceptInvite - Database error: %v", err)
+		return nil, fmt.Errorf("failed to accept invite: %v", err)
+	}
+	if invite == nil {
+		log.Printf("OrganizationService.AcceptInvite - No pending invite for user: %s", userID)
+		return nil, ErrInvalidInvite
+	}
+
+	m, err := s.orgRepo.AddMembership(ctx, &repo.Membership{
+		OrganizationID: invite.OrganizationID,
+		UserID:         uID,
+		Role:           invite.Role,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("OrganizationService.AcceptInvite - Database error: %v", err)
+		return nil, fmt.Errorf("failed to add member: %v", err)
+	}
+
+	org, err := s.orgRepo.GetOrganizationByID(ctx, invite.OrganizationID)
+	if err != nil {
+		log.Printf("OrganizationService.AcceptInvite - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch organization: %v", err)
+	}
+	if org == nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	log.Printf("OrganizationService.AcceptInvite - User %s joined organization %s as %s", userID, org.ID.Hex(), m.Role)
+
+	return &model.ResponseOrganization{
+		ID:        org.ID.Hex(),
+		Name:      org.Name,
+		Role:      m.Role,
+		CreatedAt: org.CreatedAt,
+	}, nil
+}
+
+// Authorize returns the user's membership of the organization if their role
+// is at least minRole. Non-members get ErrOrganizationNotFound so that the
+// organization's existence isn't leaked; members below minRole get
+// ErrForbidden.
+func (s *OrganizationService) Authorize(ctx context.Context, orgID bson.ObjectID, userID bson.ObjectID, minRole string) (*repo.Membership, error) {
+	m, err := s.orgRepo.GetMembership(ctx, orgID, userID)
+	if err != nil {
+		log.Printf("OrganizationService.Authorize - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch membership: %v", err)
+	}
+	if m == nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	if model.RoleRank(m.Role) < model.RoleRank(minRole) {
+		return nil, ErrForbidden
+	}
+
+	return m, nil
+}
+
+func (s *OrganizationService) authorizeHex(ctx context.Context, orgId string, userID string, minRole string) (*repo.Membership, error) {
+	oID, err := bson.ObjectIDFromHex(orgId)
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	uID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	return s.Authorize(ctx, oID, uID, minRole)
+}
+
+// manageMember checks that userID may manage memberId's membership and
+// assign it role, returning the target membership.
+func (s *OrganizationService) manageMember(ctx context.Context, orgId string, userID string, memberId string, role string) (*repo.Membership, error) {
+	actor, err := s.authorizeHex(ctx, orgId, userID, model.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	mID, err := bson.ObjectIDFromHex(memberId)
+	if err != nil {
+		return nil, ErrMemberNotFound
+	}
+
+	if mID == actor.UserID {
+		return nil, ErrForbidden
+	}
+
+	target, err := s.orgRepo.GetMembership(ctx, actor.OrganizationID, mID)
+	if err != nil {
+		log.Printf("OrganizationService.manageMember - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch member: %v", err)
+	}
+	if target == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	if actor.Role != model.RoleOwner && (target.Role == model.RoleOwner || role == model.RoleOwner) {
+		return nil, ErrForbidden
+	}
+
+	return target, nil
+}
+
+func toModelMember(m repo.Membership, user repo.User) model.ResponseMember {
+	return model.ResponseMember{
+		UserID:    m.UserID.Hex(),
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
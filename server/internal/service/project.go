@@ -2,27 +2,44 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 	"time"
 
 	model "github.com/dorianneto/bugfy/internal/api/model"
 	repo "github.com/dorianneto/bugfy/internal/repository"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+var (
+	ErrProjectNotFound    = errors.New("project not found")
+	ErrProjectKeyNotFound = errors.New("project key not found")
+	ErrInvalidProjectKey  = errors.New("invalid project key")
+)
+
+// keyTouchInterval bounds how often a key's last_used_at is written.
+const keyTouchInterval = time.Minute
+
 type ProjectService struct {
-	projectRepo *repo.ProjectRepository
-	timeout     time.Duration
+	projectRepo         *repo.ProjectRepository
+	organizationService *OrganizationService
+	timeout             time.Duration
 }
 
-func NewProjectService(projectRepo *repo.ProjectRepository) *ProjectService {
+func NewProjectService(projectRepo *repo.ProjectRepository, organizationService *OrganizationService) *ProjectService {
 	return &ProjectService{
-		projectRepo: projectRepo,
-		timeout:     time.Duration(2) * time.Second,
+		projectRepo:         projectRepo,
+		organizationService: organizationService,
+		timeout:             time.Duration(2) * time.Second,
 	}
 }
 
-func (s *ProjectService) CreateProject(ctx context.Context, req model.RequestCreateProject) (*model.ResponseCreateProject, error) {
+func (s *ProjectService) CreateProject(ctx context.Context, userID string, req model.RequestCreateProject) (*model.ResponseCreateProject, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
@@ -33,8 +50,38 @@ func (s *ProjectService) CreateProject(ctx context.Context, req model.RequestCre
 		return nil, fmt.Errorf("title is required")
 	}
 
+	ownerID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+
+	orgID, err := bson.ObjectIDFromHex(req.OrganizationID)
+	if err != nil {
+		log.Printf("ProjectService.CreateProject - Validation failed: invalid organization id")
+		return nil, fmt.Errorf("organization_id is required")
+	}
+
+	_, err = s.organizationService.Authorize(ctx, orgID, ownerID, model.RoleAdmin)
+	if err != nil {
+		log.Printf("ProjectService.CreateProject - Authorization failed: %v", err)
+		return nil, err
+	}
+
+	publicKey, err := util.GenerateKey()
+	if err != nil {
+		log.Printf("ProjectService.CreateProject - Key generation failed: %v", err)
+		return nil, fmt.Errorf("failed to create project key")
+	}
+
 	p := &repo.Project{
-		Title:     req.Title,
+		Title:          req.Title,
+		OrganizationID: orgID,
+		OwnerID:        ownerID,
+		Keys: []repo.ProjectKey{{
+			ID:        bson.NewObjectID(),
+			PublicKey: publicKey,
+			CreatedAt: time.Now(),
+		}},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -47,14 +94,393 @@ func (s *ProjectService) CreateProject(ctx context.Context, req model.RequestCre
 
 	log.Printf("ProjectService.CreateProject - Project created successfully in database: %s", project.ID.String())
 
-	return &model.ResponseCreateProject{
-		ID:        project.ID.String(),
-		Title:     project.Title,
-		CreatedAt: project.CreatedAt,
-		UpdatedAt: project.UpdatedAt,
+	return toModelProject(project), nil
+}
+
+func (s *ProjectService) GetProjects(ctx context.Context, orgId string, userID string) ([]model.ResponseCreateProject, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	membership, err := s.organizationService.authorizeHex(ctx, orgId, userID, model.RoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.projectRepo.FindProjectsByOrganization(ctx, membership.OrganizationID)
+	if err != nil {
+		log.Printf("ProjectService.GetProjects - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch projects: %v", err)
+	}
+
+	projects := make([]model.ResponseCreateProject, 0, len(p))
+
+	for _, project := range p {
+		projects = append(projects, *toModelProject(&project))
+	}
+
+	return projects, nil
+}
+
+// AuthorizeProject returns the project if userID may access it with at
+// least minRole in the project's organization, or is the owner of a project
+// created before organizations existed. Projects that don't exist and
+// projects the user isn't a member for are both reported as
+// ErrProjectNotFound so their existence isn't leaked; members without
+// minRole get ErrForbidden.
+func (s *ProjectService) AuthorizeProject(ctx context.Context, projectId string, userID string, minRole string) (*repo.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	uID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	project, err := s.projectRepo.GetProjectByID(ctx, pID)
+	if err != nil {
+		log.Printf("ProjectService.AuthorizeProject - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch project: %v", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	if project.OrganizationID.IsZero() {
+		if project.OwnerID != uID {
+			return nil, ErrProjectNotFound
+		}
+		return project, nil
+	}
+
+	_, err = s.organizationService.Authorize(ctx, project.OrganizationID, uID, minRole)
+	if errors.Is(err, ErrOrganizationNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *ProjectService) GetGroupingRules(ctx context.Context, projectId string) (*model.ResponseGroupingRules, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	project, err := s.projectRepo.GetProjectByID(ctx, pID)
+	if err != nil {
+		log.Printf("ProjectService.GetGroupingRules - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch project: %v", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	return &model.ResponseGroupingRules{
+		ProjectID: project.ID.Hex(),
+		Rules:     toModelGroupingRules(project.GroupingRules),
+	}, nil
+}
+
+func (s *ProjectService) UpdateGroupingRules(ctx context.Context, projectId string, req model.RequestUpdateGroupingRules) (*model.ResponseGroupingRules, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("ProjectService.UpdateGroupingRules - Updating %d rules for project: %s", len(req.Rules), projectId)
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	rules := make([]repo.GroupingRule, 0, len(req.Rules))
+
+	for i, rule := range req.Rules {
+		if err := validateGroupingRule(rule); err != nil {
+			log.Printf("ProjectService.UpdateGroupingRules - Validation failed for rule %d: %v", i, err)
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+
+		rules = append(rules, repo.GroupingRule{
+			Matcher:     rule.Matcher,
+			Key:         rule.Key,
+			Pattern:     rule.Pattern,
+			Fingerprint: rule.Fingerprint,
+		})
+	}
+
+	project, err := s.projectRepo.UpdateGroupingRules(ctx, pID, rules)
+	if err != nil {
+		log.Printf("ProjectService.UpdateGroupingRules - Database error: %v", err)
+		return nil, fmt.Errorf("failed to update grouping rules: %v", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	return &model.ResponseGroupingRules{
+		ProjectID: project.ID.Hex(),
+		Rules:     toModelGroupingRules(project.GroupingRules),
 	}, nil
 }
 
+func (s *ProjectService) CreateProjectKey(ctx context.Context, projectId string, req model.RequestCreateProjectKey) (*model.ResponseProjectKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("ProjectService.CreateProjectKey - Creating key for project: %s", projectId)
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	if req.RateLimit < 0 {
+		return nil, fmt.Errorf("rate limit must not be negative")
+	}
+
+	publicKey, err := util.GenerateKey()
+	if err != nil {
+		log.Printf("ProjectService.CreateProjectKey - Key generation failed: %v", err)
+		return nil, fmt.Errorf("failed to create project key")
+	}
+
+	key := repo.ProjectKey{
+		ID:        bson.NewObjectID(),
+		Label:     req.Label,
+		PublicKey: publicKey,
+		RateLimit: req.RateLimit,
+		CreatedAt: time.Now(),
+	}
+
+	if req.WithSecret {
+		key.SecretKey, err = util.GenerateKey()
+		if err != nil {
+			log.Printf("ProjectService.CreateProjectKey - Key generation failed: %v", err)
+			return nil, fmt.Errorf("failed to create project key")
+		}
+	}
+
+	project, err := s.projectRepo.AddProjectKey(ctx, pID, key)
+	if err != nil {
+		log.Printf("ProjectService.CreateProjectKey - Database error: %v", err)
+		return nil, fmt.Errorf("failed to create project key: %v", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	log.Printf("ProjectService.CreateProjectKey - Key created successfully: %s", key.ID.Hex())
+
+	return toModelProjectKey(project, key), nil
+}
+
+func (s *ProjectService) GetProjectKeys(ctx context.Context, projectId string) ([]model.ResponseProjectKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	project, err := s.projectRepo.GetProjectByID(ctx, pID)
+	if err != nil {
+		log.Printf("ProjectService.GetProjectKeys - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch project: %v", err)
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	keys := make([]model.ResponseProjectKey, 0, len(project.Keys))
+
+	for _, k := range project.Keys {
+		keys = append(keys, *toModelProjectKey(project, k))
+	}
+
+	return keys, nil
+}
+
+func (s *ProjectService) RevokeProjectKey(ctx context.Context, projectId string, keyId string) (*model.ResponseProjectKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("ProjectService.RevokeProjectKey - Revoking key %s of project: %s", keyId, projectId)
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	kID, err := bson.ObjectIDFromHex(keyId)
+	if err != nil {
+		return nil, ErrProjectKeyNotFound
+	}
+
+	project, err := s.projectRepo.RevokeProjectKey(ctx, pID, kID)
+	if err != nil {
+		log.Printf("ProjectService.RevokeProjectKey - Database error: %v", err)
+		return nil, fmt.Errorf("failed to revoke project key: %v", err)
+	}
+	if project == nil {
+		return nil, ErrProjectKeyNotFound
+	}
+
+	for _, k := range project.Keys {
+		if k.ID == kID {
+			return toModelProjectKey(project, k), nil
+		}
+	}
+
+	return nil, ErrProjectKeyNotFound
+}
+
+// AuthenticateKey resolves the project and key for publicKey. Unknown and
+// revoked keys are rejected, as is a wrong secret for keys that carry one.
+func (s *ProjectService) AuthenticateKey(ctx context.Context, publicKey string, secretKey string) (*repo.Project, *repo.ProjectKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if publicKey == "" {
+		return nil, nil, ErrInvalidProjectKey
+	}
+
+	project, err := s.projectRepo.GetProjectByKey(ctx, publicKey)
+	if err != nil {
+		log.Printf("ProjectService.AuthenticateKey - Database error: %v", err)
+		return nil, nil, fmt.Errorf("failed to authenticate key: %v", err)
+	}
+	if project == nil {
+		return nil, nil, ErrInvalidProjectKey
+	}
+
+	for _, k := range project.Keys {
+		if k.PublicKey != publicKey {
+			continue
+		}
+
+		if k.RevokedAt != nil {
+			log.Printf("ProjectService.AuthenticateKey - Revoked key used for project: %s", project.ID.Hex())
+			return nil, nil, ErrInvalidProjectKey
+		}
+
+		if k.SecretKey != "" && subtle.ConstantTimeCompare([]byte(k.SecretKey), []byte(secretKey)) != 1 {
+			log.Printf("ProjectService.AuthenticateKey - Secret mismatch for project: %s", project.ID.Hex())
+			return nil, nil, ErrInvalidProjectKey
+		}
+
+		now := time.Now()
+		if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > keyTouchInterval {
+			if err := s.projectRepo.TouchProjectKey(ctx, k.PublicKey, now); err != nil {
+				log.Printf("ProjectService.AuthenticateKey - Database error: %v", err)
+			}
+		}
+
+		return project, &k, nil
+	}
+
+	return nil, nil, ErrInvalidProjectKey
+}
+
+func toModelProjectKey(project *repo.Project, k repo.ProjectKey) *model.ResponseProjectKey {
+	return &model.ResponseProjectKey{
+		ID:         k.ID.Hex(),
+		Label:      k.Label,
+		PublicKey:  k.PublicKey,
+		SecretKey:  k.SecretKey,
+		DSN:        util.BuildDSN(k.PublicKey, dsnProjectID(project)),
+		RateLimit:  k.RateLimit,
+		Active:     k.RevokedAt == nil,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func toModelProject(project *repo.Project) *model.ResponseCreateProject {
+	return &model.ResponseCreateProject{
+		ID:             project.ID.Hex(),
+		Title:          project.Title,
+		OrganizationID: project.OrganizationID.Hex(),
+		OwnerID:        project.OwnerID.Hex(),
+		DSN:            projectDSN(project),
+		CreatedAt:      project.CreatedAt,
+		UpdatedAt:      project.UpdatedAt,
+	}
+}
+
+// dsnProjectID is the project ID of project's DSNs: its SentryID, or its
+// ObjectID until it has been numbered.
+func dsnProjectID(project *repo.Project) string {
+	if project.SentryID == 0 {
+		return project.ID.Hex()
+	}
+
+	return strconv.FormatInt(project.SentryID, 10)
+}
+
+// projectDSN returns the DSN of the first active key of project.
+func projectDSN(project *repo.Project) string {
+	for _, k := range project.Keys {
+		if k.RevokedAt == nil {
+			return util.BuildDSN(k.PublicKey, dsnProjectID(project))
+		}
+	}
+
+	return ""
+}
+
+func validateGroupingRule(rule model.GroupingRule) error {
+	switch rule.Matcher {
+	case model.GroupingMatcherMessage, model.GroupingMatcherModule:
+		if rule.Pattern == "" {
+			return fmt.Errorf("pattern is required")
+		}
+	case model.GroupingMatcherContext:
+		if rule.Key == "" {
+			return fmt.Errorf("key is required")
+		}
+	default:
+		return fmt.Errorf("unknown matcher %q", rule.Matcher)
+	}
+
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	if len(rule.Fingerprint) == 0 {
+		return fmt.Errorf("fingerprint is required")
+	}
+
+	return nil
+}
+
+func toModelGroupingRules(rules []repo.GroupingRule) []model.GroupingRule {
+	r := make([]model.GroupingRule, 0, len(rules))
+
+	for _, rule := range rules {
+		r = append(r, model.GroupingRule{
+			Matcher:     rule.Matcher,
+			Key:         rule.Key,
+			Pattern:     rule.Pattern,
+			Fingerprint: rule.Fingerprint,
+		})
+	}
+
+	return r
+}
+
 // func (s *ProjectService) DeleteUser(ctx context.Context, id uuid.UUID) error {
 // 	return s.projectRepo.DeleteUser(ctx, id)
 // }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	"github.com/dorianneto/bugfy/util"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const maxReleaseLength = 200
+
+type ReleaseService struct {
+	releaseRepo *repo.ReleaseRepository
+	timeout     time.Duration
+}
+
+func NewReleaseService(releaseRepo *repo.ReleaseRepository) *ReleaseService {
+	return &ReleaseService{
+		releaseRepo: releaseRepo,
+		timeout:     time.Duration(2) * time.Second,
+	}
+}
+
+func (s *ReleaseService) CreateRelease(ctx context.Context, projectId string, req model.RequestCreateRelease) (*model.ResponseRelease, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("ReleaseService.CreateRelease - Creating release %s for project: %s", req.Version, projectId)
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	version, err := normalizeRelease(req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+
+	rel, err := s.releaseRepo.UpsertRelease(ctx, pID, version, time.Now())
+	if err != nil {
+		log.Printf("ReleaseService.CreateRelease - Database error: %v", err)
+		return nil, fmt.Errorf("failed to create release: %v", err)
+	}
+
+	return toModelRelease(*rel), nil
+}
+
+func (s *ReleaseService) GetReleases(ctx context.Context, projectId string) ([]model.ResponseRelease, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	pID, err := bson.ObjectIDFromHex(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %v", err)
+	}
+
+	r, err := s.releaseRepo.FindReleasesByProject(ctx, pID)
+	if err != nil {
+		log.Printf("ReleaseService.GetReleases - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch releases: %v", err)
+	}
+
+	releases := make([]model.ResponseRelease, 0, len(r))
+
+	for _, rel := range r {
+		releases = append(releases, *toModelRelease(rel))
+	}
+
+	return releases, nil
+}
+
+// RecordRelease registers that an event of version was received at seenAt.
+func (s *ReleaseService) RecordRelease(ctx context.Context, projectID bson.ObjectID, version string, seenAt time.Time) error {
+	_, err := s.releaseRepo.UpsertRelease(ctx, projectID, version, seenAt)
+	if err != nil {
+		log.Printf("ReleaseService.RecordRelease - Database error: %v", err)
+		return fmt.Errorf("failed to record release: %v", err)
+	}
+
+	return nil
+}
+
+// IsAtLeast reports whether version is the same as or newer than target.
+// Semantic versions are compared by precedence; other versions are ordered
+// by when the project first saw them, so a target that hasn't been seen
+// yet is newer than every known release.
+func (s *ReleaseService) IsAtLeast(ctx context.Context, projectID bson.ObjectID, version string, target string) (bool, error) {
+	if version == target {
+		return true, nil
+	}
+
+	if c, ok := util.CompareVersions(version, target); ok {
+		return c >= 0, nil
+	}
+
+	t, err := s.releaseRepo.GetRelease(ctx, projectID, target)
+	if err != nil {
+		return false, err
+	}
+	if t == nil {
+		return false, nil
+	}
+
+	v, err := s.releaseRepo.GetRelease(ctx, projectID, version)
+	if err != nil {
+		return false, err
+	}
+	if v == nil {
+		return false, nil
+	}
+
+	return !v.FirstSeen.Before(t.FirstSeen), nil
+}
+
+// normalizeRelease trims a release version and rejects ones that can't be
+// stored.
+func normalizeRelease(version string) (string, error) {
+	version = strings.TrimSpace(version)
+
+	if len(version) > maxReleaseLength {
+		return "", fmt.Errorf("release must have at most %d characters", maxReleaseLength)
+	}
+
+	return version, nil
+}
+
+func toModelRelease(r repo.Release) *model.ResponseRelease {
+	return &model.ResponseRelease{
+		ID:        r.ID.Hex(),
+		ProjectID: r.ProjectID.Hex(),
+		Version:   r.Version,
+		FirstSeen: r.FirstSeen,
+		LastSeen:  r.LastSeen,
+	}
+}
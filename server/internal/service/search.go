@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dorianneto/bugfy/internal/api/model"
+	repo "github.com/dorianneto/bugfy/internal/repository"
+)
+
+const maxQueryLength = 1024
+
+// issueQuery is a parsed issue search. Text holds the free-text terms in
+// the syntax of a Mongo $text search; EventValues holds the conditions
+// matched against the tags or context of an issue's events.
+type issueQuery struct {
+	Status      *model.IssueState
+	Release     string
+	Environment string
+	LevelRank   int
+	Text        string
+	EventValues map[string]string
+}
+
+// parseIssueQuery parses a search such as
+//
+//	is:unresolved release:1.4.2 user.email:foo@bar.com "timeout"
+//
+// "is", "release", "environment" and "level" filter the issue itself, any
+// other key:value pair filters on the tags or context of its events, and
+// the remaining words and quoted phrases are searched for in the issue
+// title.
+func parseIssueQuery(query string) (*issueQuery, error) {
+	if len(query) > maxQueryLength {
+		return nil, fmt.Errorf("%w: query must have at most %d characters", ErrInvalidIssueQuery, maxQueryLength)
+	}
+
+	tokens, err := splitQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &issueQuery{}
+	var text []string
+
+	for _, token := range tokens {
+		key, value, ok := strings.Cut(token, ":")
+		if !ok || key == "" || strings.HasPrefix(key, `"`) {
+			text = append(text, token)
+			continue
+		}
+
+		value = unquote(value)
+		if value == "" {
+			return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidIssueQuery, key)
+		}
+
+		switch key {
+		case "is":
+			status, err := model.ParseIssueState(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidIssueQuery, err)
+			}
+			q.Status = &status
+		case "release":
+			q.Release = value
+		case "environment":
+			q.Environment = value
+		case "level":
+			q.LevelRank = model.LevelRank(value)
+			if q.LevelRank == 0 {
+				return nil, fmt.Errorf("%w: unknown level %q", ErrInvalidIssueQuery, value)
+			}
+		default:
+			if q.EventValues == nil {
+				q.EventValues = map[string]string{}
+			}
+			q.EventValues[key] = value
+		}
+	}
+
+	q.Text = strings.Join(text, " ")
+
+	return q, nil
+}
+
+// apply adds the issue-level conditions of q to filter, overriding the
+// ones already set.
+func (q *issueQuery) apply(filter *repo.IssueFilter) {
+	if q.Status != nil {
+		filter.Status = q.Status
+	}
+	if q.Release != "" {
+		filter.Release = q.Release
+	}
+	if q.Environment != "" {
+		filter.Environment = q.Environment
+	}
+	if q.LevelRank > 0 {
+		filter.LevelRank = q.LevelRank
+	}
+
+	filter.Text = q.Text
+}
+
+// splitQuery splits a query on whitespace outside double quotes. Quotes
+// are kept so phrases can be told apart from single words.
+func splitQuery(query string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidIssueQuery)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
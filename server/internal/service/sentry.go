@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dorianneto/bugfy/internal/api/model"
+)
+
+// CreateSentryEvent stores an event sent by a Sentry SDK. The event is
+// translated to a RequestCreateError and goes through the same validation
+// and grouping as the events sent to the Bugfy API. The response carries
+// the SDK's event ID, or the stored event's ID when the SDK sent none.
+func (s *ErrorService) CreateSentryEvent(ctx context.Context, projectId string, event model.SentryEvent) (*model.ResponseSentryEvent, error) {
+	req := fromSentryEvent(event)
+	req.ProjectID = projectId
+
+	e, err := s.createError(ctx, req)
+	if err != nil {
+		log.Printf("ErrorService.CreateSentryEvent - Failed to create event %s: %v", event.EventID, err)
+		return nil, err
+	}
+
+	log.Printf("ErrorService.CreateSentryEvent - Event %s stored as: %s", event.EventID, e.ID.Hex())
+
+	id := strings.ToLower(strings.ReplaceAll(event.EventID, "-", ""))
+	if id == "" {
+		id = e.ID.Hex()
+	}
+
+	return &model.ResponseSentryEvent{ID: id}, nil
+}
+
+// fromSentryEvent translates a Sentry event. Values Sentry accepts but
+// Bugfy would reject, such as unknown levels or tag keys, are dropped
+// rather than failing the whole event; an environment Bugfy can't index is
+// kept in the context instead.
+func fromSentryEvent(event model.SentryEvent) model.RequestCreateError {
+	req := model.RequestCreateError{
+		Message:     string(event.Message),
+		Release:     event.Release,
+		Tags:        fromSentryTags(event.Tags),
+		Extra:       event.Extra,
+		Runtime:     event.Contexts.Runtime,
+		OS:          event.Contexts.OS,
+		Device:      event.Contexts.Device,
+		Breadcrumbs: fromSentryBreadcrumbs(event.Breadcrumbs),
+		Fingerprint: event.Fingerprint,
+	}
+
+	if req.Message == "" && event.LogEntry != nil {
+		req.Message = event.LogEntry.Formatted
+		if req.Message == "" {
+			req.Message = event.LogEntry.Message
+		}
+	}
+
+	if model.LevelRank(event.Level) > 0 {
+		req.Level = event.Level
+	}
+
+	values := map[string]string{
+		"platform":    event.Platform,
+		"logger":      event.Logger,
+		"server_name": event.ServerName,
+		"transaction": event.Transaction,
+	}
+	for k, v := range values {
+		if v == "" {
+			delete(values, k)
+		}
+	}
+	if environment, err := normalizeEnvironment(event.Environment); err == nil {
+		req.Environment = environment
+	} else {
+		values["environment"] = truncate(event.Environment, maxTagValueLength)
+	}
+
+	if len(values) > 0 {
+		req.Context = values
+	}
+
+	// The last exception is the one raised; the ones before it are its
+	// causes.
+	stacktrace := event.Stacktrace
+	if n := len(event.Exception); n > 0 {
+		exception := event.Exception[n-1]
+		req.Exception = &model.Exception{Type: exception.Type, Value: exception.Value}
+		if exception.Stacktrace != nil {
+			stacktrace = exception.Stacktrace
+		}
+	}
+	if stacktrace != nil {
+		req.StackTrace = fromSentryFrames(stacktrace.Frames)
+	}
+
+	if u := event.User; u != nil {
+		req.User = &model.EventUser{
+			ID:        string(u.ID),
+			Email:     u.Email,
+			IPAddress: u.IPAddress,
+			Username:  u.Username,
+		}
+	}
+
+	if r := event.Request; r != nil {
+		req.Request = &model.EventRequest{
+			URL:     r.URL,
+			Method:  r.Method,
+			Headers: r.Headers,
+			Query:   r.QueryString,
+			Body:    r.Data,
+		}
+	}
+
+	return req
+}
+
+// fromSentryFrames keeps the innermost maxStackFrames frames that have a
+// function, module or filename.
+func fromSentryFrames(frames []model.SentryFrame) []model.StackFrame {
+	f := make([]model.StackFrame, 0, len(frames))
+
+	for _, frame := range frames {
+		filename := frame.Filename
+		if filename == "" {
+			filename = frame.AbsPath
+		}
+		if frame.Function == "" && frame.Module == "" && filename == "" {
+			continue
+		}
+
+		f = append(f, model.StackFrame{
+			Function:    frame.Function,
+			Module:      frame.Module,
+			Filename:    filename,
+			Line:        frame.Lineno,
+			Column:      frame.Colno,
+			InApp:       frame.InApp,
+			PreContext:  frame.PreContext,
+			ContextLine: frame.ContextLine,
+			PostContext: frame.PostContext,
+		})
+	}
+
+	if len(f) > maxStackFrames {
+		f = f[len(f)-maxStackFrames:]
+	}
+
+	return f
+}
+
+// fromSentryTags keeps the first maxTags valid tags in key order,
+// truncating long values.
+func fromSentryTags(tags model.SentryMap) map[string]string {
+	t := map[string]string{}
+
+	for k, v := range tags {
+		if len(k) > maxTagKeyLength || !tagKeyPattern.MatchString(k) || v == "" {
+			continue
+		}
+
+		t[k] = truncate(v, maxTagValueLength)
+	}
+
+	if len(t) > maxTags {
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys[maxTags:] {
+			delete(t, k)
+		}
+	}
+
+	return t
+}
+
+func fromSentryBreadcrumbs(breadcrumbs []model.SentryBreadcrumb) []model.Breadcrumb {
+	b := make([]model.Breadcrumb, 0, len(breadcrumbs))
+
+	for _, crumb := range breadcrumbs {
+		category := crumb.Category
+		if category == "" {
+			category = crumb.Type
+		}
+
+		level := crumb.Level
+		if model.LevelRank(level) == 0 {
+			level = ""
+		}
+
+		b = append(b, model.Breadcrumb{
+			Timestamp: time.Time(crumb.Timestamp),
+			Category:  category,
+			Level:     level,
+			Message:   crumb.Message,
+			Data:      crumb.Data,
+		})
+	}
+
+	return b
+}
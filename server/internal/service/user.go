@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -10,8 +11,11 @@ import (
 	repo "github.com/dorianneto/bugfy/internal/repository"
 	"github.com/dorianneto/bugfy/util"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+var ErrUserExists = errors.New("a user with this email already exists")
+
 type JWTClaims struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
@@ -19,14 +23,16 @@ type JWTClaims struct {
 }
 
 type UserService struct {
-	userRepo *repo.UserRepository
-	timeout  time.Duration
+	userRepo            *repo.UserRepository
+	organizationService *OrganizationService
+	timeout             time.Duration
 }
 
-func NewUserService(userRepo *repo.UserRepository) *UserService {
+func NewUserService(userRepo *repo.UserRepository, organizationService *OrganizationService) *UserService {
 	return &UserService{
-		userRepo: userRepo,
-		timeout:  time.Duration(2) * time.Second,
+		userRepo:            userRepo,
+		organizationService: organizationService,
+		timeout:             time.Duration(2) * time.Second,
 	}
 }
 
@@ -36,6 +42,8 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 
 	log.Printf("UserService.CreateUser - Starting user creation for: %s", req.Email)
 
+	req.Email = normalizeEmail(req.Email)
+
 	if req.Username == "" || req.Email == "" || req.Password == "" {
 		log.Printf("UserService.CreateUser - Validation failed: missing required fields")
 		return nil, fmt.Errorf("username, email, and password are required")
@@ -46,6 +54,13 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 		return nil, fmt.Errorf("password must be at least 6 characters")
 	}
 
+	if req.InviteToken != "" {
+		if err := s.organizationService.CheckInvite(ctx, req.InviteToken, req.Email); err != nil {
+			log.Printf("UserService.CreateUser - Invite check failed: %v", err)
+			return nil, err
+		}
+	}
+
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
 		log.Printf("UserService.CreateUser - Password hashing failed: %v", err)
@@ -61,6 +76,10 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 	}
 
 	user, err := s.userRepo.CreateUser(ctx, u)
+	if errors.Is(err, repo.ErrDuplicateUser) {
+		log.Printf("UserService.CreateUser - Email already registered: %s", req.Email)
+		return nil, ErrUserExists
+	}
 	if err != nil {
 		log.Printf("UserService.CreateUser - Database error: %v", err)
 		return nil, fmt.Errorf("failed to create user: %v", err)
@@ -68,17 +87,15 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 
 	log.Printf("UserService.CreateUser - User created successfully in database: %s", user.ID.String())
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
-		ID:       user.ID.String(),
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    user.ID.String(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		},
-	})
+	if req.InviteToken != "" {
+		_, err = s.organizationService.AcceptInvite(ctx, req.InviteToken, user.ID.Hex())
+		if err != nil {
+			log.Printf("UserService.CreateUser - Invite redemption failed: %v", err)
+			return nil, fmt.Errorf("failed to accept invite: %v", err)
+		}
+	}
 
-	secretKey := util.GetEnv("secretKey", "")
-	ss, err := token.SignedString([]byte(secretKey))
+	ss, err := signToken(user)
 	if err != nil {
 		return nil, err
 	}
@@ -86,64 +103,92 @@ func (s *UserService) CreateUser(ctx context.Context, req model.RequestCreateUse
 	return &model.ResponseLoginUser{
 		AccessToken: ss,
 		Username:    user.Username,
-		ID:          user.ID.String(),
+		ID:          user.ID.Hex(),
 	}, nil
 }
 
-// func (s *UserService) Login(ctx context.Context, req model.RequestLoginUser) (*model.ResponseLoginUser, error) {
-// 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
-// 	defer cancel()
+func (s *UserService) Login(ctx context.Context, req model.RequestLoginUser) (*model.ResponseLoginUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
 
-// 	log.Printf("UserService.Login - Starting login attempt for email: %s", req.Email)
+	log.Printf("UserService.Login - Starting login attempt for email: %s", req.Email)
 
-// 	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
-// 	if err != nil {
-// 		log.Printf("UserService.Login - Database error: %v", err)
-// 		return nil, fmt.Errorf("failed to authenticate user")
-// 	}
+	user, err := s.userRepo.GetUserByEmail(ctx, normalizeEmail(req.Email))
+	if err != nil {
+		log.Printf("UserService.Login - Database error: %v", err)
+		return nil, fmt.Errorf("failed to authenticate user")
+	}
 
-// 	if user == nil {
-// 		log.Printf("UserService.Login - User not found for email: %s", req.Email)
-// 		return nil, fmt.Errorf("invalid email or password")
-// 	}
+	if user == nil {
+		log.Printf("UserService.Login - User not found for email: %s", req.Email)
+		return nil, fmt.Errorf("invalid email or password")
+	}
 
-// 	if user.PasswordHash == nil {
-// 		log.Printf("UserService.Login - User has no password hash: %s", req.Email)
-// 		return nil, fmt.Errorf("invalid user account")
-// 	}
+	if user.PasswordHash == nil {
+		log.Printf("UserService.Login - User has no password hash: %s", req.Email)
+		return nil, fmt.Errorf("invalid user account")
+	}
 
-// 	err = util.CheckPassword(req.Password, *user.PasswordHash)
-// 	if err != nil {
-// 		log.Printf("UserService.Login - Password check failed for user: %s", user.ID.String())
-// 		return nil, fmt.Errorf("invalid email or password")
-// 	}
+	err = util.CheckPassword(req.Password, *user.PasswordHash)
+	if err != nil {
+		log.Printf("UserService.Login - Password check failed for user: %s", user.ID.Hex())
+		return nil, fmt.Errorf("invalid email or password")
+	}
 
-// 	log.Printf("UserService.Login - Password verified successfully for user: %s", user.ID.String())
+	log.Printf("UserService.Login - Password verified successfully for user: %s", user.ID.Hex())
 
-// 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
-// 		ID:       user.ID.String(),
-// 		Username: user.Username,
-// 		RegisteredClaims: jwt.RegisteredClaims{
-// 			Issuer:    user.ID.String(),
-// 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-// 		},
-// 	})
+	ss, err := signToken(user)
+	if err != nil {
+		log.Printf("UserService.Login - JWT signing failed for user: %s, error: %v", user.ID.Hex(), err)
+		return nil, fmt.Errorf("failed to generate authentication token")
+	}
 
-// 	secretKey := util.GetEnv("secretKey", "")
+	log.Printf("UserService.Login - Login successful for user: %s (%s)", user.ID.Hex(), user.Username)
+	return &model.ResponseLoginUser{AccessToken: ss, Username: user.Username, ID: user.ID.Hex()}, nil
+}
 
-// 	ss, err := token.SignedString([]byte(secretKey))
-// 	if err != nil {
-// 		log.Printf("UserService.Login - JWT signing failed for user: %s, error: %v", user.ID.String(), err)
-// 		return nil, fmt.Errorf("failed to generate authentication token")
-// 	}
+func (s *UserService) GetUser(ctx context.Context, userID string) (*model.ResponseGetUser, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
 
-// 	log.Printf("UserService.Login - Login successful for user: %s (%s)", user.ID.String(), user.Username)
-// 	return &model.ResponseLoginUser{AccessToken: ss, Username: user.Username, ID: user.ID.String()}, nil
-// }
+	id, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
 
-// func (s *UserService) GetUserByID(ctx context.Context, id bson.ObjectID) (*repo.User, error) {
-// 	return s.userRepo.GetUserByID(ctx, id)
-// }
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		log.Printf("UserService.GetUser - Database error: %v", err)
+		return nil, fmt.Errorf("failed to fetch user: %v", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return &model.ResponseGetUser{
+		ID:        user.ID.Hex(),
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	}, nil
+}
+
+// signToken issues the session JWT stored in the jwt cookie. The id claim
+// holds the hex user ID read back by middleware.JWTAuth.
+func signToken(user *repo.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
+		ID:       user.ID.Hex(),
+		Username: user.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    user.ID.Hex(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+		},
+	})
+
+	secretKey := util.GetEnv("secretKey", "")
+
+	return token.SignedString([]byte(secretKey))
+}
 
 // func (s *UserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
 // 	return s.userRepo.DeleteUser(ctx, id)
@@ -35,17 +35,51 @@ func main() {
 	projectRepo := repo.NewProjectRepository(dbConn)
 	errorRepo := repo.NewErrorRepository(dbConn)
 	issueRepo := repo.NewIssueRepository(dbConn)
+	organizationRepo := repo.NewOrganizationRepository(dbConn)
+	activityRepo := repo.NewActivityRepository(dbConn)
+	releaseRepo := repo.NewReleaseRepository(dbConn)
 
-	userService := service.NewUserService(userRepo)
-	projectService := service.NewProjectService(projectRepo)
-	issueService := service.NewIssueService(issueRepo)
-	errorService := service.NewErrorService(errorRepo, issueService)
+	if err := userRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := issueRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := projectRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := organizationRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := activityRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := errorRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := releaseRepo.EnsureIndexes(context.TODO()); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := errorRepo.BackfillIssueIDs(context.TODO()); err != nil {
+		log.Printf("Failed to link errors to issues: %v", err)
+	}
+	if err := projectRepo.BackfillSentryIDs(context.TODO()); err != nil {
+		log.Printf("Failed to number projects: %v", err)
+	}
+
+	organizationService := service.NewOrganizationService(organizationRepo, userRepo)
+	userService := service.NewUserService(userRepo, organizationService)
+	projectService := service.NewProjectService(projectRepo, organizationService)
+	releaseService := service.NewReleaseService(releaseRepo)
+	issueService := service.NewIssueService(issueRepo, activityRepo, errorRepo, releaseService)
+	errorService := service.NewErrorService(errorRepo, projectRepo, issueService, releaseService, projectService)
 
 	userHandler := handler.NewUserHandler(userService)
-	projectHandler := handler.NewProjectHandler(projectService, issueService)
+	projectHandler := handler.NewProjectHandler(projectService, issueService, releaseService)
 	errorHandler := handler.NewErrorHandler(errorService)
+	organizationHandler := handler.NewOrganizationHandler(organizationService, projectService)
 
-	router := router.SetupRouter(userHandler, projectHandler, errorHandler)
+	router := router.SetupRouter(userHandler, projectHandler, errorHandler, organizationHandler, projectService)
 	if err := http.ListenAndServe(":8080", router); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
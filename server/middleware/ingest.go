@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "github.com/dorianneto/bugfy/internal/repository"
+	service "github.com/dorianneto/bugfy/internal/service"
+	"github.com/dorianneto/bugfy/util"
+	"github.com/go-chi/chi/v5"
+)
+
+// IngestAuth authenticates error ingestion requests with a project key and
+// stores the resolved project ID in the request context. The key is read
+// from the X-Bugfy-Key header ("<public>" or "<public>:<secret>"), from an
+// "Authorization: DSN <dsn>" header, or the way Sentry SDKs send it: an
+// X-Sentry-Auth header or sentry_key and sentry_secret query parameters.
+// When the route has a {project_id}, it must be the key's project. Keys
+// with a rate limit are throttled per minute.
+func IngestAuth(projectService *service.ProjectService) func(http.Handler) http.Handler {
+	limiter := newKeyLimiter()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			publicKey, secretKey, dsnProjectID := projectKeyFromRequest(r)
+			if publicKey == "" {
+				util.WriteError(w, http.StatusUnauthorized, "missing project key")
+				return
+			}
+
+			project, key, err := projectService.AuthenticateKey(r.Context(), publicKey, secretKey)
+			if errors.Is(err, service.ErrInvalidProjectKey) {
+				util.WriteError(w, http.StatusUnauthorized, "invalid project key")
+				return
+			}
+			if err != nil {
+				log.Printf("IngestAuth - Service error: %v", err)
+				util.WriteError(w, http.StatusInternalServerError, "failed to authenticate project key")
+				return
+			}
+
+			if dsnProjectID != "" && !isProjectID(project, dsnProjectID) {
+				util.WriteError(w, http.StatusUnauthorized, "project key does not match DSN")
+				return
+			}
+			if pathProjectID := chi.URLParam(r, "project_id"); pathProjectID != "" && !isProjectID(project, pathProjectID) {
+				util.WriteError(w, http.StatusUnauthorized, "project key does not match project")
+				return
+			}
+
+			if key.RateLimit > 0 {
+				ok, reset := limiter.allow(key.ID.Hex(), key.RateLimit, time.Now())
+				if !ok {
+					w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+					util.WriteError(w, http.StatusTooManyRequests, "rate limit exceeded")
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), "projectID", project.ID.Hex())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// isProjectID reports whether id identifies project, either by its ObjectID
+// or by the numeric SentryID of its DSNs.
+func isProjectID(project *repo.Project, id string) bool {
+	if id == project.ID.Hex() {
+		return true
+	}
+
+	return project.SentryID != 0 && id == strconv.FormatInt(project.SentryID, 10)
+}
+
+func projectKeyFromRequest(r *http.Request) (publicKey string, secretKey string, projectID string) {
+	if key := r.Header.Get("X-Bugfy-Key"); key != "" {
+		publicKey, secretKey, _ = strings.Cut(key, ":")
+		return publicKey, secretKey, ""
+	}
+
+	if auth := r.Header.Get("X-Sentry-Auth"); auth != "" {
+		publicKey, secretKey = parseSentryAuth(auth)
+		return publicKey, secretKey, ""
+	}
+
+	if key := r.URL.Query().Get("sentry_key"); key != "" {
+		return key, r.URL.Query().Get("sentry_secret"), ""
+	}
+
+	scheme, dsn, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Sentry") {
+		publicKey, secretKey = parseSentryAuth(r.Header.Get("Authorization"))
+		return publicKey, secretKey, ""
+	}
+	if !ok || !strings.EqualFold(scheme, "DSN") {
+		return "", "", ""
+	}
+
+	u, err := url.Parse(strings.TrimSpace(dsn))
+	if err != nil || u.User == nil {
+		return "", "", ""
+	}
+
+	secretKey, _ = u.User.Password()
+
+	return u.User.Username(), secretKey, strings.Trim(u.Path, "/")
+}
+
+// parseSentryAuth reads the keys from a Sentry auth header such as
+//
+//	Sentry sentry_version=7, sentry_client=sentry.python/2.0, sentry_key=<public>
+func parseSentryAuth(header string) (publicKey string, secretKey string) {
+	header = strings.TrimSpace(header)
+	if scheme, params, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Sentry") {
+		header = params
+	}
+
+	for _, param := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch strings.TrimSpace(k) {
+		case "sentry_key":
+			publicKey = strings.TrimSpace(v)
+		case "sentry_secret":
+			secretKey = strings.TrimSpace(v)
+		}
+	}
+
+	return publicKey, secretKey
+}
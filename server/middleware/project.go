@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	service "github.com/dorianneto/bugfy/internal/service"
+	"github.com/dorianneto/bugfy/util"
+	"github.com/go-chi/chi/v5"
+)
+
+// ProjectAccess rejects requests for the {id} project unless the user set by
+// JWTAuth has at least minRole on it. Inaccessible projects respond 404, like
+// missing ones; members without minRole get 403.
+func ProjectAccess(projectService *service.ProjectService, minRole string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value("userID").(string)
+			if !ok {
+				util.WriteError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			_, err := projectService.AuthorizeProject(r.Context(), chi.URLParam(r, "id"), userID, minRole)
+			if errors.Is(err, service.ErrProjectNotFound) {
+				util.WriteError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, service.ErrForbidden) {
+				util.WriteError(w, http.StatusForbidden, err.Error())
+				return
+			}
+			if err != nil {
+				log.Printf("ProjectAccess - Service error: %v", err)
+				util.WriteError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
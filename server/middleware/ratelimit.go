@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// keyLimiter enforces per-key event limits over fixed one-minute windows.
+// Counters are kept in memory, so limits apply per server instance.
+type keyLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*limitWindow
+}
+
+type limitWindow struct {
+	start time.Time
+	count int
+}
+
+func newKeyLimiter() *keyLimiter {
+	return &keyLimiter{windows: make(map[string]*limitWindow)}
+}
+
+// allow records one event for key and reports whether it is within limit
+// events per minute, along with the time the current window resets.
+func (l *keyLimiter) allow(key string, limit int, now time.Time) (bool, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &limitWindow{start: now.Truncate(time.Minute)}
+		l.windows[key] = w
+	}
+
+	reset := w.start.Add(time.Minute)
+	if w.count >= limit {
+		return false, reset
+	}
+
+	w.count++
+
+	return true, reset
+}
@@ -4,12 +4,15 @@ import (
 	"net/http"
 
 	handler "github.com/dorianneto/bugfy/internal/api/handler"
+	"github.com/dorianneto/bugfy/internal/api/model"
+	service "github.com/dorianneto/bugfy/internal/service"
+	internalMiddleware "github.com/dorianneto/bugfy/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
-func SetupRouter(userHandler *handler.UserHandler, projectHandler *handler.ProjectHandler, errorHandler *handler.ErrorHandler) http.Handler {
+func SetupRouter(userHandler *handler.UserHandler, projectHandler *handler.ProjectHandler, errorHandler *handler.ErrorHandler, organizationHandler *handler.OrganizationHandler, projectService *service.ProjectService) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
@@ -18,8 +21,8 @@ func SetupRouter(userHandler *handler.UserHandler, projectHandler *handler.Proje
 	r.Use(middleware.RealIP)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "http://127.0.0.1:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Bugfy-Key", "X-Sentry-Auth"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -27,24 +30,85 @@ func SetupRouter(userHandler *handler.UserHandler, projectHandler *handler.Proje
 
 	r.Route("/api/users", func(u chi.Router) {
 		u.Post("/signup", userHandler.CreateUser)
-		// u.Post("/login", userHandler.Login)
-		// u.Get("/logout", userHandler.Logout)
+		u.Post("/login", userHandler.Login)
+		u.Post("/logout", userHandler.Logout)
 
-		// u.Group(func(r chi.Router) {
-		// 	r.Use(internalMiddleware.JWTAuth)
-		// 	r.Put("/username", userHandler.UpdateUsername)
-		// })
+		u.Group(func(r chi.Router) {
+			r.Use(internalMiddleware.JWTAuth)
+			r.Get("/me", userHandler.Me)
+			// r.Put("/username", userHandler.UpdateUsername)
+		})
+	})
+
+	r.Route("/api/organizations", func(u chi.Router) {
+		u.Use(internalMiddleware.JWTAuth)
+		u.Post("/", organizationHandler.CreateOrganization)
+		u.Get("/", organizationHandler.GetOrganizations)
+		u.Get("/{id}/members", organizationHandler.GetMembers)
+		u.Put("/{id}/members/{userId}", organizationHandler.UpdateMember)
+		u.Delete("/{id}/members/{userId}", organizationHandler.RemoveMember)
+		u.Post("/{id}/invites", organizationHandler.CreateInvite)
+		u.Get("/{id}/projects", organizationHandler.GetProjects)
+	})
+
+	r.Route("/api/invites", func(u chi.Router) {
+		u.Use(internalMiddleware.JWTAuth)
+		u.Post("/{token}/accept", organizationHandler.AcceptInvite)
 	})
 
 	r.Route("/api/projects", func(u chi.Router) {
+		u.Use(internalMiddleware.JWTAuth)
 		u.Post("/", projectHandler.CreateProject)
-		u.Get("/{id}/issues", projectHandler.GetIssues)
+
+		u.Route("/{id}", func(p chi.Router) {
+			// Members triage issues; changing how the project ingests and
+			// groups events, or rewriting its issues, takes an admin.
+			p.Group(func(m chi.Router) {
+				m.Use(internalMiddleware.ProjectAccess(projectService, model.RoleMember))
+				m.Get("/issues", projectHandler.GetIssues)
+				m.Patch("/issues", projectHandler.BulkUpdateIssues)
+				m.Patch("/issues/{issueId}", projectHandler.UpdateIssue)
+				m.Get("/issues/{issueId}/activity", projectHandler.GetIssueActivity)
+				m.Get("/issues/{issueId}/tags", projectHandler.GetIssueTags)
+				m.Get("/issues/{issueId}/events", errorHandler.GetIssueEvents)
+				m.Get("/issues/{issueId}/events/latest", errorHandler.GetLatestEvent)
+				m.Get("/issues/{issueId}/events/oldest", errorHandler.GetOldestEvent)
+				m.Get("/grouping-rules", projectHandler.GetGroupingRules)
+				m.Get("/releases", projectHandler.GetReleases)
+			})
+
+			p.Group(func(a chi.Router) {
+				a.Use(internalMiddleware.ProjectAccess(projectService, model.RoleAdmin))
+				a.Post("/issues/merge", projectHandler.MergeIssues)
+				a.Post("/issues/{issueId}/unmerge", projectHandler.UnmergeIssue)
+				a.Put("/grouping-rules", projectHandler.UpdateGroupingRules)
+				a.Post("/keys", projectHandler.CreateKey)
+				a.Get("/keys", projectHandler.GetKeys)
+				a.Delete("/keys/{keyId}", projectHandler.RevokeKey)
+				a.Post("/releases", projectHandler.CreateRelease)
+			})
+		})
+	})
+
+	r.Route("/api/events", func(u chi.Router) {
+		u.Use(internalMiddleware.JWTAuth)
+		u.Get("/{eventId}", errorHandler.GetEvent)
 	})
 
 	r.Route("/api/errors", func(u chi.Router) {
+		u.Use(internalMiddleware.IngestAuth(projectService))
 		u.Post("/", errorHandler.CreateError)
 	})
 
+	// Sentry SDKs report to /api/{project_id}/store/ and /envelope/ when
+	// their DSN points at Bugfy; project_id is the project's numeric
+	// SentryID.
+	r.Route("/api/{project_id}", func(u chi.Router) {
+		u.Use(internalMiddleware.IngestAuth(projectService))
+		u.Post("/store/", errorHandler.StoreSentryEvent)
+		u.Post("/envelope/", errorHandler.StoreSentryEnvelope)
+	})
+
 	r.Get("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
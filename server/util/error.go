@@ -3,13 +3,191 @@ package util
 import (
 	"crypto/sha256"
 	"fmt"
+	"path"
+	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-func GenerateFingerprint(message string) string {
+// FingerprintVersion identifies the grouping algorithm used by
+// GenerateFingerprint. Bump it whenever the derived keys change so issues
+// grouped by an older algorithm can be told apart.
+const FingerprintVersion = 4
+
+var (
+	uuidPattern    = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	addressPattern = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`)
+	hashPattern    = regexp.MustCompile(`(?i)\b[0-9a-f]{7,}\b`)
+	numberPattern  = regexp.MustCompile(`\d+(\.\d+)?`)
+	spacePattern   = regexp.MustCompile(`\s+`)
+)
+
+type FingerprintFrame struct {
+	Function string
+	Module   string
+	Filename string
+	InApp    bool
+}
+
+type FingerprintInput struct {
+	Type    string
+	Message string
+	Frames  []FingerprintFrame
+}
+
+// GenerateFingerprint derives the grouping key of an event. Events with a
+// stack trace are grouped by exception type and their in-app frames, so line
+// numbers and message details don't split an issue. Events without frames
+// fall back to the exception type and the normalized message.
+func GenerateFingerprint(in FingerprintInput) string {
 	var parts []string
-	parts = append(parts, message)
+	parts = append(parts, fmt.Sprintf("v%d", FingerprintVersion), in.Type)
+
+	frames := groupingFrames(in.Frames)
+	if len(frames) > 0 {
+		for _, f := range frames {
+			parts = append(parts, normalizeFrame(f))
+		}
+	} else {
+		parts = append(parts, NormalizeMessage(in.Message))
+	}
+
+	return hashParts(parts)
+}
+
+// NormalizeMessage strips the variable parts of an error message (quoted
+// values, UUIDs, addresses, hashes and numbers) so that messages differing
+// only by interpolated values produce the same string.
+func NormalizeMessage(message string) string {
+	m := replaceQuoted(message)
+	m = uuidPattern.ReplaceAllString(m, "<uuid>")
+	m = addressPattern.ReplaceAllString(m, "<addr>")
+	m = hashPattern.ReplaceAllString(m, "<hex>")
+	m = numberPattern.ReplaceAllString(m, "<num>")
+	m = spacePattern.ReplaceAllString(m, " ")
+
+	return strings.TrimSpace(m)
+}
+
+// replaceQuoted replaces the values quoted with ", ' or ` by "<str>". A
+// quote only opens a value when it doesn't follow a word character and
+// only closes one when no word character follows it, so apostrophes such as
+// in "can't" are left alone.
+func replaceQuoted(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isQuote(c) || (i > 0 && isWordRune(lastRune(s[:i]))) {
+			b.WriteByte(c)
+			continue
+		}
+
+		end := closingQuote(s, i)
+		if end < 0 {
+			b.WriteByte(c)
+			continue
+		}
+
+		b.WriteString("<str>")
+		i = end
+	}
+
+	return b.String()
+}
+
+// closingQuote returns the index of the quote closing the one at open, or
+// -1 if it isn't closed.
+func closingQuote(s string, open int) int {
+	for i := open + 1; i < len(s); i++ {
+		if s[i] == s[open] && (i+1 == len(s) || !isWordRune(firstRune(s[i+1:]))) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isQuote(c byte) bool {
+	return c == '"' || c == '\'' || c == '`'
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// ExpandFingerprint builds a grouping key from explicit fingerprint parts.
+// The "{{ default }}" placeholder is replaced by the server-computed key, so
+// ["{{ default }}"] groups exactly like the default algorithm would.
+func ExpandFingerprint(parts []string, defaultKey string) string {
+	if len(parts) == 0 {
+		return defaultKey
+	}
+
+	expanded := make([]string, 0, len(parts))
+
+	for _, p := range parts {
+		if isDefaultPlaceholder(p) {
+			p = defaultKey
+		}
+		expanded = append(expanded, p)
+	}
+
+	if len(expanded) == 1 && isDefaultPlaceholder(parts[0]) {
+		return defaultKey
+	}
+
+	return hashParts(append([]string{"custom"}, expanded...))
+}
+
+func isDefaultPlaceholder(part string) bool {
+	p := strings.TrimSpace(part)
+	if !strings.HasPrefix(p, "{{") || !strings.HasSuffix(p, "}}") {
+		return false
+	}
+
+	return strings.TrimSpace(p[2:len(p)-2]) == "default"
+}
+
+func groupingFrames(frames []FingerprintFrame) []FingerprintFrame {
+	var inApp []FingerprintFrame
+
+	for _, f := range frames {
+		if f.InApp {
+			inApp = append(inApp, f)
+		}
+	}
+
+	if len(inApp) > 0 {
+		return inApp
+	}
+
+	return frames
+}
+
+func normalizeFrame(f FingerprintFrame) string {
+	location := f.Module
+	if location == "" && f.Filename != "" {
+		location = hashPattern.ReplaceAllString(path.Base(f.Filename), "<hex>")
+	}
+
+	function := addressPattern.ReplaceAllString(f.Function, "<addr>")
+
+	return location + ":" + function
+}
 
+func hashParts(parts []string) string {
 	data := strings.Join(parts, "|")
 	hash := sha256.Sum256([]byte(data))
 	return fmt.Sprintf("%x", hash)[:16]
@@ -0,0 +1,147 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/dorianneto/bugfy/util"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"double quotes", `key "user:42" not found`, "key <str> not found"},
+		{"single quotes", `can't open 'config.yml': denied`, "can't open <str>: denied"},
+		{"backticks", "column `email` is ambiguous", "column <str> is ambiguous"},
+		{"apostrophes", "can't connect, don't retry", "can't connect, don't retry"},
+		{"apostrophe inside quotes", `'it's' is invalid`, "<str> is invalid"},
+		{"unclosed quote", `unexpected " in input`, `unexpected " in input`},
+		{"quote inside word", `got a"b"c`, `got a"b"c`},
+		{"uuid", "order 3f2504e0-4f89-11d3-9a0c-0305e82c3301 failed", "order <uuid> failed"},
+		{"address", "nil pointer at 0xc000012345", "nil pointer at <addr>"},
+		{"hash", "commit deadbeef1 missing", "commit <hex> missing"},
+		{"numbers", "timeout after 1.5s on port 8080", "timeout after <num>s on port <num>"},
+		{"whitespace", "  too   many\tspaces ", "too many spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := util.NormalizeMessage(tt.message); got != tt.want {
+				t.Errorf("NormalizeMessage(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateFingerprint(t *testing.T) {
+	handler := util.FingerprintFrame{Function: "handle", Module: "app.api", InApp: true}
+	query := util.FingerprintFrame{Function: "query", Module: "app.db", InApp: true}
+	library := util.FingerprintFrame{Function: "serve", Module: "net.http"}
+
+	tests := []struct {
+		name string
+		a, b util.FingerprintInput
+		same bool
+	}{
+		{
+			"frames ignore the message",
+			util.FingerprintInput{Type: "KeyError", Message: "missing 'a'", Frames: []util.FingerprintFrame{handler}},
+			util.FingerprintInput{Type: "KeyError", Message: "unrelated", Frames: []util.FingerprintFrame{handler}},
+			true,
+		},
+		{
+			"library frames are ignored when in-app frames exist",
+			util.FingerprintInput{Type: "KeyError", Frames: []util.FingerprintFrame{library, handler}},
+			util.FingerprintInput{Type: "KeyError", Frames: []util.FingerprintFrame{handler}},
+			true,
+		},
+		{
+			"different in-app frames",
+			util.FingerprintInput{Type: "KeyError", Frames: []util.FingerprintFrame{handler}},
+			util.FingerprintInput{Type: "KeyError", Frames: []util.FingerprintFrame{handler, query}},
+			false,
+		},
+		{
+			"library frames are used without in-app frames",
+			util.FingerprintInput{Type: "KeyError", Frames: []util.FingerprintFrame{library}},
+			util.FingerprintInput{Type: "KeyError", Message: "x"},
+			false,
+		},
+		{
+			"file names ignore directories and hashes",
+			util.FingerprintInput{Frames: []util.FingerprintFrame{{Function: "f", Filename: "/a/main.3f9a1c2b.js"}}},
+			util.FingerprintInput{Frames: []util.FingerprintFrame{{Function: "f", Filename: "/b/main.77e0d4a1.js"}}},
+			true,
+		},
+		{
+			"exception types split frames",
+			util.FingerprintInput{Type: "KeyError", Frames: []util.FingerprintFrame{handler}},
+			util.FingerprintInput{Type: "ValueError", Frames: []util.FingerprintFrame{handler}},
+			false,
+		},
+		{
+			"without frames the normalized message is used",
+			util.FingerprintInput{Type: "KeyError", Message: "missing 'a' in row 1"},
+			util.FingerprintInput{Type: "KeyError", Message: "missing 'b' in row 2"},
+			true,
+		},
+		{
+			"without frames different messages split",
+			util.FingerprintInput{Type: "KeyError", Message: "can't connect"},
+			util.FingerprintInput{Type: "KeyError", Message: "don't retry"},
+			false,
+		},
+		{
+			"without frames or message the exception type is used",
+			util.FingerprintInput{Type: "KeyError"},
+			util.FingerprintInput{Type: "ValueError"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := util.GenerateFingerprint(tt.a), util.GenerateFingerprint(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("fingerprints %s and %s: same = %v, want %v", a, b, a == b, tt.same)
+			}
+		})
+	}
+}
+
+func TestExpandFingerprint(t *testing.T) {
+	const defaultKey = "0123456789abcdef"
+
+	tests := []struct {
+		name  string
+		parts []string
+		want  func(got string) bool
+	}{
+		{"no parts", nil, func(got string) bool { return got == defaultKey }},
+		{"default only", []string{"{{ default }}"}, func(got string) bool { return got == defaultKey }},
+		{"default without spaces", []string{"{{default}}"}, func(got string) bool { return got == defaultKey }},
+		{"custom", []string{"database-down"}, func(got string) bool { return got != defaultKey }},
+		{"default extended", []string{"{{ default }}", "tenant-1"}, func(got string) bool {
+			return got != defaultKey && got != util.ExpandFingerprint([]string{"{{ default }}", "tenant-2"}, defaultKey)
+		}},
+		{"default extended with the same values", []string{"{{ default }}", "tenant-1"}, func(got string) bool {
+			return got == util.ExpandFingerprint([]string{"{{default}}", "tenant-1"}, defaultKey)
+		}},
+		{"default extended follows the default key", []string{"{{ default }}", "tenant-1"}, func(got string) bool {
+			return got != util.ExpandFingerprint([]string{"{{ default }}", "tenant-1"}, "fedcba9876543210")
+		}},
+		{"custom ignores the default key", []string{"database-down"}, func(got string) bool {
+			return got == util.ExpandFingerprint([]string{"database-down"}, "fedcba9876543210")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := util.ExpandFingerprint(tt.parts, defaultKey); !tt.want(got) {
+				t.Errorf("ExpandFingerprint(%q) = %q", tt.parts, got)
+			}
+		})
+	}
+}
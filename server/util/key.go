@@ -0,0 +1,31 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+)
+
+func GenerateKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// BuildDSN returns the DSN clients use to report errors to a project, in the
+// form <scheme>://<public key>@<host>/<project id>.
+func BuildDSN(publicKey string, projectID string) string {
+	u, err := url.Parse(GetEnv("PUBLIC_URL", "http://localhost:8080"))
+	if err != nil {
+		return ""
+	}
+
+	u.User = url.User(publicKey)
+	u.Path = "/" + projectID
+
+	return u.String()
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+)
+
+type semver struct {
+	parts      [3]int
+	prerelease string
+}
+
+// CompareVersions compares two release versions as semantic versions. A
+// "package@" prefix, a leading "v" and build metadata are ignored. ok is
+// false when either version isn't a semantic version.
+func CompareVersions(a string, b string) (result int, ok bool) {
+	va, ok := parseSemver(a)
+	if !ok {
+		return 0, false
+	}
+
+	vb, ok := parseSemver(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := range va.parts {
+		if va.parts[i] != vb.parts[i] {
+			if va.parts[i] < vb.parts[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+
+	switch {
+	case va.prerelease == vb.prerelease:
+		return 0, true
+	case va.prerelease == "":
+		return 1, true
+	case vb.prerelease == "":
+		return -1, true
+	}
+
+	return comparePrerelease(va.prerelease, vb.prerelease), true
+}
+
+// comparePrerelease compares prereleases identifier by identifier, as
+// semver specifies: numeric identifiers numerically and below alphanumeric
+// ones, others as strings, and a shorter prerelease below a longer one it
+// prefixes.
+func comparePrerelease(a string, b string) int {
+	ia := strings.Split(a, ".")
+	ib := strings.Split(b, ".")
+
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		na, errA := strconv.ParseUint(ia[i], 10, 64)
+		nb, errB := strconv.ParseUint(ib[i], 10, 64)
+
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(ia[i], ib[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return cmp.Compare(len(ia), len(ib))
+}
+
+func parseSemver(version string) (semver, bool) {
+	var v semver
+
+	if i := strings.LastIndex(version, "@"); i >= 0 {
+		version = version[i+1:]
+	}
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, v.prerelease, _ = strings.Cut(version, "-")
+
+	parts := strings.Split(version, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, false
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.parts[i] = n
+	}
+
+	return v, true
+}